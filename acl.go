@@ -0,0 +1,94 @@
+package wirenet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HubPolicy is used to allow the client to open the stream of the other client through the hub.
+// The caller is the identification of the client which opens the stream,
+// the target is the identification of the client which registered the stream.
+// If the policy returns the error, the stream is denied. See WithHubPolicy().
+type HubPolicy func(caller, target Identification, streamName string) error
+
+// ACLRule allows or denies the streams matched by the caller, the target and the stream name.
+// The patterns can contain the wildcard * which matches any sequence, the empty pattern matches any value.
+type ACLRule struct {
+	Caller string
+	Target string
+	Stream string
+	Allow  bool
+}
+
+// ACL is the declarative hub policy, the first matched rule is applied.
+// The stream is denied if no rule is matched.
+//
+//	wirenet.WithHubPolicy(wirenet.ACL{
+//		{Caller: "admin-*", Allow: true},
+//		{Target: "*", Stream: "stats", Allow: true},
+//	}.Policy)
+type ACL []ACLRule
+
+// Policy returns ErrPermissionDenied if the stream is denied.
+func (acl ACL) Policy(caller, target Identification, streamName string) error {
+	for _, rule := range acl {
+		if matchWildcard(rule.Caller, string(caller)) &&
+			matchWildcard(rule.Target, string(target)) &&
+			matchWildcard(rule.Stream, streamName) {
+			if rule.Allow {
+				return nil
+			}
+			break
+		}
+	}
+	return ErrPermissionDenied
+}
+
+// allowedProviders returns the providers of the stream allowed by the hub policy for the session, see providers().
+func (s *session) allowedProviders(peer Identification, streamName string) ([]Session, error) {
+	providers, err := s.w.providers(peer, streamName)
+	if err != nil || s.w.hubPolicy == nil {
+		return providers, err
+	}
+	caller := s.Identification()
+	allowed := make([]Session, 0, len(providers))
+	var denied error
+	for _, sess := range providers {
+		if err := s.w.hubPolicy(caller, sess.Identification(), streamName); err != nil {
+			denied = err
+			continue
+		}
+		allowed = append(allowed, sess)
+	}
+	if len(allowed) == 0 {
+		if errors.Is(denied, ErrPermissionDenied) {
+			return nil, denied
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPermissionDenied, denied)
+	}
+	return allowed, nil
+}
+
+func matchWildcard(pattern, s string) bool {
+	if len(pattern) == 0 || pattern == "*" {
+		return true
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
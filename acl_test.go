@@ -0,0 +1,159 @@
+package wirenet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{pattern: "", s: "any", match: true},
+		{pattern: "*", s: "", match: true},
+		{pattern: "shell", s: "shell", match: true},
+		{pattern: "shell", s: "shell2", match: false},
+		{pattern: "admin-*", s: "admin-1", match: true},
+		{pattern: "admin-*", s: "guest-1", match: false},
+		{pattern: "*.stats", s: "node.stats", match: true},
+		{pattern: "api/*/read", s: "api/v1/users/read", match: true},
+		{pattern: "api/*/read", s: "api/v1/write", match: false},
+		{pattern: "a*a", s: "a", match: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.match, matchWildcard(tc.pattern, tc.s), tc.pattern+" "+tc.s)
+	}
+}
+
+func TestACL_Policy(t *testing.T) {
+	acl := ACL{
+		{Caller: "guest", Stream: "shell", Allow: false},
+		{Caller: "admin-*", Allow: true},
+		{Target: "node*", Stream: "stats", Allow: true},
+		{Caller: "*", Target: "guest", Stream: "*", Allow: true},
+	}
+	testCases := []struct {
+		caller string
+		target string
+		stream string
+		err    error
+	}{
+		{caller: "admin-1", target: "node1", stream: "shell"},
+		{caller: "guest", target: "node1", stream: "stats"},
+		{caller: "guest", target: "node1", stream: "shell", err: ErrPermissionDenied},
+		{caller: "node2", target: "node1", stream: "shell", err: ErrPermissionDenied},
+		{caller: "node2", target: "guest", stream: "shell"},
+		{caller: "", target: "node1", stream: "stats"},
+	}
+	for _, tc := range testCases {
+		err := acl.Policy(Identification(tc.caller), Identification(tc.target), tc.stream)
+		assert.Equal(t, tc.err, err, tc.caller+" "+tc.target+" "+tc.stream)
+	}
+}
+
+func TestHub_Policy(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	var mu sync.Mutex
+	var denied []error
+	hub, err := Hub(addr,
+		WithHubPolicy(ACL{
+			{Caller: "admin", Allow: true},
+			{Stream: "stats", Allow: true},
+			{Target: "guest", Allow: true},
+		}.Policy),
+		WithErrorHandler(func(ctx context.Context, err error) {
+			if opErr, ok := err.(*OpError); ok && errors.Is(opErr.Err, ErrPermissionDenied) {
+				mu.Lock()
+				denied = append(denied, opErr)
+				mu.Unlock()
+			}
+		}),
+		WithConnectHook(func(closer io.Closer) {
+			close(initHub)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	var wg sync.WaitGroup
+	join := func(id string) (Wire, Session) {
+		var sess Session
+		wg.Add(1)
+		client, err := Join(addr,
+			WithIdentification(Identification(id), nil),
+			WithSessionOpenHook(func(s Session) {
+				sess = s
+				wg.Done()
+			}))
+		assert.Nil(t, err)
+		for _, name := range []string{"stats", "shell"} {
+			name := name
+			client.Stream(name, func(ctx context.Context, s Stream) {
+				_, _ = s.ReadFrom(strings.NewReader(name + " of " + id))
+			})
+		}
+		go func() {
+			assert.Nil(t, client.Connect())
+		}()
+		wg.Wait()
+		return client, sess
+	}
+	node, _ := join("node")
+	guest, guestSess := join("guest")
+	admin, adminSess := join("admin")
+
+	open := func(sess Session, id, name string) (string, error) {
+		s, err := sess.OpenStreamContext(context.Background(), name, WithPeer(Identification(id)))
+		if err != nil {
+			return "", err
+		}
+		resp, err := ioutil.ReadAll(s.Reader())
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+		return string(resp), nil
+	}
+
+	resp, err := open(guestSess, "node", "stats")
+	assert.Nil(t, err)
+	assert.Equal(t, "stats of node", resp)
+
+	resp, err = open(adminSess, "node", "shell")
+	assert.Nil(t, err)
+	assert.Equal(t, "shell of node", resp)
+
+	_, err = open(guestSess, "node", "shell")
+	rerr, ok := err.(*RemoteError)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorCodePermissionDenied, rerr.Code)
+	assert.Equal(t, ErrPermissionDenied.Error(), rerr.Message)
+
+	// only the allowed providers are balanced
+	for i := 0; i < 3; i++ {
+		resp, err = open(guestSess, "", "shell")
+		assert.Nil(t, err)
+		assert.Equal(t, "shell of guest", resp)
+	}
+
+	mu.Lock()
+	assert.Len(t, denied, 1)
+	assert.True(t, bytes.Contains([]byte(denied[0].Error()), []byte("id-guest")))
+	mu.Unlock()
+
+	assert.Nil(t, admin.Close())
+	assert.Nil(t, guest.Close())
+	assert.Nil(t, node.Close())
+	assert.Nil(t, hub.Close())
+}
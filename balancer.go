@@ -0,0 +1,139 @@
+package wirenet
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// BalanceKeyHeader is the metadata key of the balance key sent by WithBalanceKey().
+const BalanceKeyHeader = "wirenet.balance-key"
+
+// Balancer picks the session of the client for the stream opening in the hub mode,
+// when several clients register the same stream name. The providers are never empty.
+// The key is sent by the caller with WithBalanceKey(), it is empty by default. See WithBalancer().
+type Balancer func(streamName, key string, providers []Session) Session
+
+// RoundRobinBalancer returns the balancer which picks the providers of each stream name in turn.
+// It is the default balancer of the hub.
+func RoundRobinBalancer() Balancer {
+	var mu sync.Mutex
+	next := make(map[string]int)
+	return func(streamName, _ string, providers []Session) Session {
+		mu.Lock()
+		defer mu.Unlock()
+		i := next[streamName] % len(providers)
+		next[streamName] = i + 1
+		return providers[i]
+	}
+}
+
+// LeastActiveBalancer picks the provider with the least active streams relayed by the hub.
+func LeastActiveBalancer(_, _ string, providers []Session) Session {
+	pick, min := providers[0], -1
+	for _, sess := range providers {
+		active := sess.(*session).activeStreamCounter()
+		if min < 0 || active < min {
+			pick, min = sess, active
+		}
+	}
+	return pick
+}
+
+// ConsistentHashBalancer picks the provider by the key, so the calls with the same key are sent to the same client
+// while it is connected. When the provider is gone, only its keys are moved to the other providers.
+func ConsistentHashBalancer(_, key string, providers []Session) Session {
+	// rendezvous hashing, the provider with the highest weight of the key wins
+	var (
+		pick Session
+		max  uint64
+	)
+	for _, sess := range providers {
+		h := fnv.New64a()
+		id := sess.ID()
+		_, _ = h.Write(id[:])
+		_, _ = h.Write([]byte(key))
+		if weight := mix64(h.Sum64()); pick == nil || weight > max {
+			pick, max = sess, weight
+		}
+	}
+	return pick
+}
+
+// mix64 spreads the bits of the FNV hash, the keys often differ only in the last bytes.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// WithBalanceKey sets the key of the stream used by ConsistentHashBalancer() in the hub mode.
+// The key is delivered to the handler in the metadata with BalanceKeyHeader.
+func WithBalanceKey(key string) StreamOption {
+	return WithStreamMetadata(Metadata{BalanceKeyHeader: key})
+}
+
+// providers returns the sessions of the clients which registered the stream.
+// If the peer is not empty, only the session of the client with the identification is returned, see WithPeer().
+func (w *wire) providers(peer Identification, name string) ([]Session, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if len(w.sessions) == 0 || w.closed {
+		return nil, ErrSessionClosed
+	}
+	if len(peer) > 0 {
+		if sess, found := w.peerIndex[peerStream{id: string(peer), stream: name}]; found {
+			return []Session{sess}, nil
+		}
+		return nil, ErrSessionNotFound
+	}
+	if providers := w.streamIndex[name]; len(providers) > 0 {
+		return providers, nil
+	}
+	return nil, ErrSessionNotFound
+}
+
+// findSession picks the session of the stream with the balancer, see providers().
+func (w *wire) findSession(peer Identification, name, key string) (Session, error) {
+	providers, err := w.providers(peer, name)
+	if err != nil {
+		return nil, err
+	}
+	return w.balance(name, key, providers), nil
+}
+
+func (w *wire) balance(streamName, key string, providers []Session) Session {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return w.balancer(streamName, key, providers)
+}
+
+func (w *wire) addProvider(streamName string, s Session) {
+	for _, sess := range w.streamIndex[streamName] {
+		if sess == s {
+			return
+		}
+	}
+	w.streamIndex[streamName] = append(w.streamIndex[streamName], s)
+}
+
+func (w *wire) removeProvider(streamName string, s Session) {
+	providers := w.streamIndex[streamName]
+	for i, sess := range providers {
+		if sess != s {
+			continue
+		}
+		// the slice is shared with the callers of providers(), so it is copied
+		rest := make([]Session, 0, len(providers)-1)
+		rest = append(rest, providers[:i]...)
+		rest = append(rest, providers[i+1:]...)
+		if len(rest) == 0 {
+			delete(w.streamIndex, streamName)
+		} else {
+			w.streamIndex[streamName] = rest
+		}
+		return
+	}
+}
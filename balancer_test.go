@@ -0,0 +1,274 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testProviders(active ...int) []Session {
+	providers := make([]Session, 0, len(active))
+	for _, n := range active {
+		providers = append(providers, &session{id: uuid.New(), activeStreams: n})
+	}
+	return providers
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	providers := testProviders(0, 0, 0)
+	balancer := RoundRobinBalancer()
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, providers[i%3], balancer("a", "", providers))
+	}
+	// each stream name has its own turn
+	assert.Equal(t, providers[0], balancer("b", "", providers))
+	assert.Equal(t, providers[1], balancer("b", "", providers[:2]))
+	assert.Equal(t, providers[0], balancer("b", "", providers[:2]))
+}
+
+func TestLeastActiveBalancer(t *testing.T) {
+	providers := testProviders(3, 1, 2, 1)
+	assert.Equal(t, providers[1], LeastActiveBalancer("a", "", providers))
+	assert.Equal(t, providers[0], LeastActiveBalancer("a", "", providers[:1]))
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	providers := testProviders(0, 0, 0, 0)
+	picks := make(map[string]Session)
+	used := make(map[Session]bool)
+	for i := 0; i < 100; i++ {
+		key := "user" + strconv.Itoa(i)
+		picks[key] = ConsistentHashBalancer("a", key, providers)
+		used[picks[key]] = true
+		assert.Equal(t, picks[key], ConsistentHashBalancer("a", key, providers))
+	}
+	assert.Len(t, used, len(providers))
+
+	// only the keys of the removed provider are moved
+	rest := providers[1:]
+	for key, pick := range picks {
+		if pick != providers[0] {
+			assert.Equal(t, pick, ConsistentHashBalancer("a", key, rest))
+		}
+	}
+}
+
+func TestHub_Balancer(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	hub, err := Hub(addr, WithConnectHook(func(closer io.Closer) {
+		close(initHub)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	var wg sync.WaitGroup
+	join := func(id string) (Wire, Session) {
+		var sess Session
+		wg.Add(1)
+		client, err := Join(addr,
+			WithIdentification(Identification(id), nil),
+			WithSessionOpenHook(func(s Session) {
+				sess = s
+				wg.Done()
+			}))
+		assert.Nil(t, err)
+		client.Stream("stats", func(ctx context.Context, s Stream) {
+			_, _ = s.ReadFrom(strings.NewReader(id))
+		})
+		go func() {
+			assert.Nil(t, client.Connect())
+		}()
+		wg.Wait()
+		return client, sess
+	}
+	node1, _ := join("node1")
+	node2, _ := join("node2")
+	caller, sess := join("caller")
+
+	open := func(opts ...StreamOption) string {
+		s, err := sess.OpenStreamContext(context.Background(), "stats", opts...)
+		assert.Nil(t, err)
+		resp, err := ioutil.ReadAll(s.Reader())
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+		return string(resp)
+	}
+
+	// the streams are spread over all providers
+	served := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		served[open()]++
+	}
+	assert.Equal(t, map[string]int{"node1": 2, "node2": 2, "caller": 2}, served)
+
+	// the other providers are kept when one of them is gone
+	assert.Nil(t, node1.Close())
+	assert.Eventually(t, func() bool {
+		providers, err := hub.(*wire).providers(nil, "stats")
+		return err == nil && len(providers) == 2
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, "node1", open())
+	}
+
+	assert.Nil(t, node2.Close())
+	assert.Nil(t, caller.Close())
+	assert.Nil(t, hub.Close())
+}
+
+func TestHub_ConsistentHashBalancer(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	hub, err := Hub(addr,
+		WithBalancer(ConsistentHashBalancer),
+		WithConnectHook(func(closer io.Closer) {
+			close(initHub)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	var wg sync.WaitGroup
+	clients := make([]Wire, 0, 3)
+	var sess Session
+	for i := 0; i < 3; i++ {
+		id := "node" + strconv.Itoa(i)
+		wg.Add(1)
+		client, err := Join(addr,
+			WithIdentification(Identification(id), nil),
+			WithSessionOpenHook(func(s Session) {
+				sess = s
+				wg.Done()
+			}))
+		assert.Nil(t, err)
+		client.Stream("stats", func(ctx context.Context, s Stream) {
+			_, _ = s.ReadFrom(strings.NewReader(id + " " + s.Metadata().Get(BalanceKeyHeader)))
+		})
+		go func() {
+			assert.Nil(t, client.Connect())
+		}()
+		wg.Wait()
+		clients = append(clients, client)
+	}
+
+	for _, key := range []string{"user1", "user2", "user3"} {
+		var first string
+		for i := 0; i < 3; i++ {
+			s, err := sess.OpenStreamContext(context.Background(), "stats", WithBalanceKey(key))
+			assert.Nil(t, err)
+			resp, err := ioutil.ReadAll(s.Reader())
+			assert.Nil(t, err)
+			assert.Nil(t, s.Close())
+			assert.Contains(t, string(resp), " "+key)
+			if i == 0 {
+				first = string(resp)
+			}
+			assert.Equal(t, first, string(resp))
+		}
+	}
+
+	for _, client := range clients {
+		assert.Nil(t, client.Close())
+	}
+	assert.Nil(t, hub.Close())
+}
+
+func TestHub_LeastActiveBalancer(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	hub, err := Hub(addr,
+		WithBalancer(LeastActiveBalancer),
+		WithConnectHook(func(closer io.Closer) {
+			close(initHub)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	var wg sync.WaitGroup
+	served := make(chan string, 8)
+	clients := make([]Wire, 0, 3)
+	var sess Session
+	for _, id := range []string{"node1", "node2", "caller"} {
+		id := id
+		wg.Add(1)
+		client, err := Join(addr,
+			WithIdentification(Identification(id), nil),
+			WithSessionOpenHook(func(s Session) {
+				sess = s
+				wg.Done()
+			}))
+		assert.Nil(t, err)
+		if id != "caller" {
+			client.Stream("wait", func(ctx context.Context, s Stream) {
+				served <- id
+				<-ctx.Done()
+			})
+		}
+		go func() {
+			assert.Nil(t, client.Connect())
+		}()
+		wg.Wait()
+		clients = append(clients, client)
+	}
+
+	// waits until the hub counts the relayed streams, the handler can be called before
+	waitActive := func(n int) {
+		assert.Eventually(t, func() bool {
+			total := 0
+			for _, sess := range hub.Sessions() {
+				total += sess.(*session).activeStreamCounter()
+			}
+			return total == n
+		}, time.Second, 10*time.Millisecond)
+	}
+	open := func() (Stream, string) {
+		s, err := sess.OpenStream("wait")
+		assert.Nil(t, err)
+		return s, <-served
+	}
+	count := make(map[string]int)
+	streams := make(map[string][]Stream)
+	for i := 0; i < 4; i++ {
+		s, id := open()
+		waitActive(i + 1)
+		count[id]++
+		streams[id] = append(streams[id], s)
+	}
+	assert.Equal(t, map[string]int{"node1": 2, "node2": 2}, count)
+
+	// the stream closed twice is counted once, so the next stream is sent to the node with less streams
+	s := streams["node1"][0]
+	assert.Nil(t, s.Conn().Close())
+	assert.Nil(t, s.Close())
+	waitActive(3)
+	s, id := open()
+	assert.Equal(t, "node1", id)
+	assert.Nil(t, s.Close())
+	for _, held := range streams {
+		for _, s := range held {
+			assert.Nil(t, s.Close())
+		}
+	}
+
+	for _, client := range clients {
+		assert.Nil(t, client.Close())
+	}
+	assert.Nil(t, hub.Close())
+}
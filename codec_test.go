@@ -0,0 +1,36 @@
+package wirenet
+
+import (
+	"testing"
+
+	"github.com/mediabuyerbot/go-wirenet/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	Name  string
+	Value int
+}
+
+func TestCodec_MarshalUnmarshal(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		data, err := codec.Marshal(testMessage{Name: "test", Value: 1})
+		assert.Nil(t, err, codec.Name())
+		var msg testMessage
+		assert.Nil(t, codec.Unmarshal(data, &msg), codec.Name())
+		assert.Equal(t, testMessage{Name: "test", Value: 1}, msg, codec.Name())
+	}
+
+	data, err := ProtoCodec.Marshal(&wirenettest.Frame{Cmd: "test", Payload: []byte("payload")})
+	assert.Nil(t, err)
+	var frm wirenettest.Frame
+	assert.Nil(t, ProtoCodec.Unmarshal(data, &frm))
+	assert.Equal(t, "test", frm.Cmd)
+	assert.Equal(t, []byte("payload"), frm.Payload)
+}
+
+func TestProtoCodec_NotProtoMessage(t *testing.T) {
+	_, err := ProtoCodec.Marshal(testMessage{})
+	assert.Equal(t, ErrNotProtoMessage, err)
+	assert.Equal(t, ErrNotProtoMessage, ProtoCodec.Unmarshal(nil, &testMessage{}))
+}
@@ -0,0 +1,147 @@
+package wirenet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// EndpointPolicy is used to choose the order in which the client side endpoints are dialed.
+type EndpointPolicy int
+
+const (
+	// EndpointsInOrder dials the endpoints in the given order starting from the last healthy endpoint.
+	EndpointsInOrder EndpointPolicy = iota
+
+	// EndpointsRoundRobin dials the endpoints in turn, each attempt starts from the next endpoint.
+	EndpointsRoundRobin
+)
+
+type srvRecord struct {
+	service string
+	proto   string
+	name    string
+}
+
+type lookupSRVFunc func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
+// resolveEndpoints returns the static endpoints followed by the endpoints from the DNS SRV records.
+func (w *wire) resolveEndpoints(ctx context.Context) ([]string, error) {
+	endpoints := make([]string, 0, len(w.endpoints)+1)
+	if len(w.addr) > 0 || len(w.endpoints) == 0 && w.srv == nil {
+		endpoints = append(endpoints, w.addr)
+	}
+	endpoints = append(endpoints, w.endpoints...)
+	if w.srv == nil {
+		return endpoints, nil
+	}
+	_, records, err := w.lookupSRV(ctx, w.srv.service, w.srv.proto, w.srv.name)
+	if err != nil {
+		if len(endpoints) > 0 {
+			return endpoints, nil
+		}
+		return nil, err
+	}
+	for _, rec := range records {
+		host := strings.TrimSuffix(rec.Target, ".")
+		endpoints = append(endpoints, net.JoinHostPort(host, strconv.Itoa(int(rec.Port))))
+	}
+	if len(endpoints) == 0 {
+		return nil, ErrAddrEmpty
+	}
+	return endpoints, nil
+}
+
+// endpointOrder returns the dial order of the endpoints according to the endpoint policy.
+func (w *wire) endpointOrder(endpoints []string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	start := 0
+	switch w.endpointPolicy {
+	case EndpointsRoundRobin:
+		start = w.endpointNext % len(endpoints)
+		w.endpointNext = start + 1
+	default:
+		for i, endpoint := range endpoints {
+			if endpoint == w.healthyEndpoint {
+				start = i
+				break
+			}
+		}
+	}
+	order := make([]string, 0, len(endpoints))
+	order = append(order, endpoints[start:]...)
+	return append(order, endpoints[:start]...)
+}
+
+// dialEndpoints dials the endpoints until the first successful connection.
+func (w *wire) dialEndpoints(ctx context.Context) (conn net.Conn, endpoint string, err error) {
+	endpoints, err := w.resolveEndpoints(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, endpoint = range w.endpointOrder(endpoints) {
+		conn, err = w.dialEndpoint(ctx, endpoint)
+		if err == nil {
+			w.mu.Lock()
+			w.healthyEndpoint = endpoint
+			w.mu.Unlock()
+			return conn, endpoint, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, "", err
+}
+
+// dialEndpoint dials the endpoint within the dial timeout.
+func (w *wire) dialEndpoint(ctx context.Context, endpoint string) (net.Conn, error) {
+	if w.dialTimeout <= 0 {
+		return w.dial(ctx, endpoint)
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, w.dialTimeout)
+	defer cancel()
+	conn, err := w.dial(dialCtx, endpoint)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		// the endpoint is timed out, not the wire, so the connection is retried
+		return nil, &dialTimeoutError{endpoint: endpoint, err: err}
+	}
+	return conn, err
+}
+
+// dialTimeoutError is returned when the endpoint is not dialed within the dial timeout.
+type dialTimeoutError struct {
+	endpoint string
+	err      error
+}
+
+func (e *dialTimeoutError) Error() string {
+	return "wirenet: dial " + e.endpoint + ": " + e.err.Error()
+}
+
+func (e *dialTimeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *dialTimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *dialTimeoutError) Temporary() bool {
+	return true
+}
+
+func (w *wire) setEndpoint(endpoint string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.endpoint = endpoint
+}
+
+func (w *wire) Endpoint() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.endpoint
+}
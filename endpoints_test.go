@@ -0,0 +1,187 @@
+package wirenet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinAny(t *testing.T) {
+	w, err := JoinAny(nil)
+	assert.Nil(t, w)
+	assert.Equal(t, ErrAddrEmpty, err)
+
+	w, err = JoinAny([]string{":8989", ":8990"})
+	assert.Nil(t, err)
+	assert.True(t, w.(*wire).role.IsClientSide())
+	assert.Equal(t, []string{":8989", ":8990"}, w.(*wire).endpoints)
+}
+
+func TestWire_EndpointOrder(t *testing.T) {
+	endpoints := []string{"a", "b", "c"}
+
+	w, err := JoinAny(endpoints)
+	assert.Nil(t, err)
+	wr := w.(*wire)
+	assert.Equal(t, []string{"a", "b", "c"}, wr.endpointOrder(endpoints))
+	wr.healthyEndpoint = "b"
+	assert.Equal(t, []string{"b", "c", "a"}, wr.endpointOrder(endpoints))
+	assert.Equal(t, []string{"b", "c", "a"}, wr.endpointOrder(endpoints))
+
+	w, err = JoinAny(endpoints, WithEndpointPolicy(EndpointsRoundRobin))
+	assert.Nil(t, err)
+	wr = w.(*wire)
+	assert.Equal(t, []string{"a", "b", "c"}, wr.endpointOrder(endpoints))
+	assert.Equal(t, []string{"b", "c", "a"}, wr.endpointOrder(endpoints))
+	assert.Equal(t, []string{"c", "a", "b"}, wr.endpointOrder(endpoints))
+	assert.Equal(t, []string{"a", "b", "c"}, wr.endpointOrder(endpoints))
+}
+
+func TestWire_ResolveEndpoints(t *testing.T) {
+	lookupErr := errors.New("lookup error")
+	records := []*net.SRV{
+		{Target: "hub1.example.com.", Port: 8989},
+		{Target: "hub2.example.com.", Port: 8990},
+	}
+
+	w, err := Join(":7000", WithEndpoints(":7001"), WithSRV("wire", "tcp", "example.com"))
+	assert.Nil(t, err)
+	wr := w.(*wire)
+	wr.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		assert.Equal(t, "wire", service)
+		assert.Equal(t, "tcp", proto)
+		assert.Equal(t, "example.com", name)
+		return "", records, nil
+	}
+	endpoints, err := wr.resolveEndpoints(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{":7000", ":7001", "hub1.example.com:8989", "hub2.example.com:8990"}, endpoints)
+
+	// fallback to the static endpoints
+	wr.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return "", nil, lookupErr
+	}
+	endpoints, err = wr.resolveEndpoints(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{":7000", ":7001"}, endpoints)
+
+	// only srv
+	w, err = Join("", WithSRV("wire", "tcp", "example.com"))
+	assert.Nil(t, err)
+	wr = w.(*wire)
+	wr.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return "", nil, lookupErr
+	}
+	_, err = wr.resolveEndpoints(context.Background())
+	assert.Equal(t, lookupErr, err)
+}
+
+func TestWire_ConnectFailover(t *testing.T) {
+	deadAddr := "127.0.0.1" + genAddr(t)
+	addr := "127.0.0.1" + genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// server side
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+	assert.NotEmpty(t, server.Endpoint())
+
+	// client side
+	client, err := JoinAny([]string{deadAddr, addr}, WithSessionOpenHook(func(s Session) {
+		close(initCli)
+	}))
+	assert.Nil(t, err)
+	assert.Empty(t, client.Endpoint())
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	assert.Equal(t, addr, client.Endpoint())
+	assert.Equal(t, addr, client.(*wire).healthyEndpoint)
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectFailoverDialTimeout(t *testing.T) {
+	blackholeAddr := "10.255.255.1:8989"
+	addr := "127.0.0.1" + genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// the blackholed endpoint does not answer until the dial is canceled
+	var netDialer net.Dialer
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		if addr == blackholeAddr {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return netDialer.DialContext(ctx, "tcp", addr)
+	}
+
+	// server side
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := JoinAny([]string{blackholeAddr, addr},
+		WithDialer(dialer),
+		WithDialTimeout(200*time.Millisecond),
+		WithSessionOpenHook(func(s Session) {
+			close(initCli)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	select {
+	case <-initCli:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client is not failed over to the healthy endpoint")
+	}
+	assert.Equal(t, addr, client.Endpoint())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectDialTimeoutRetry(t *testing.T) {
+	blackholeAddr := "10.255.255.1:8989"
+	var attempts int32
+	client, err := Join(blackholeAddr,
+		WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			atomic.AddInt32(&attempts, 1)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		WithDialTimeout(50*time.Millisecond),
+		WithRetryMax(3),
+		WithRetryWait(10*time.Millisecond, 10*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	err = client.Connect()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, DefaultRetryableError(err))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
@@ -3,7 +3,9 @@ package wirenet
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -27,10 +29,62 @@ var (
 	// ErrSessionClosed is returned when session is closed.
 	ErrSessionClosed = errors.New("wirenet: session closed")
 
+	// ErrDeadlineExceeded is returned when the stream deadline or the read timeout is reached.
+	// The error implements net.Error with Timeout() returning true.
+	ErrDeadlineExceeded error = deadlineExceededError{}
+
+	// ErrUnknownProxyScheme is returned when the proxy scheme is not supported. See WithProxy().
+	ErrUnknownProxyScheme = errors.New("wirenet: unknown proxy scheme")
+
+	// ErrProxyAuthFailed is returned when the proxy rejects the credentials.
+	ErrProxyAuthFailed = errors.New("wirenet: proxy authentication failed")
+
+	// ErrProxyProtocol is returned when the proxy response is malformed.
+	ErrProxyProtocol = errors.New("wirenet: proxy protocol error")
+
 	// ErrUnknownCertificateName is returned when certificate name is empty. See LoadCertificates().
 	ErrUnknownCertificateName = errors.New("wirenet: unknown certificate name")
+
+	// ErrNotProtoMessage is returned by ProtoCodec when the message does not implement proto.Message.
+	ErrNotProtoMessage = errors.New("wirenet: message does not implement proto.Message")
+
+	// ErrInvalidMethod is returned when the RPC method is not func(context.Context, *Req) (*Resp, error). See Wire.Method().
+	ErrInvalidMethod = errors.New("wirenet: invalid rpc method")
+
+	// ErrMethodNotFound is returned when the RPC method is not registered.
+	ErrMethodNotFound = errors.New("wirenet: rpc method not found")
+
+	// ErrUnknownCodec is returned when the codec of the RPC calls is not supported by the peer.
+	ErrUnknownCodec = errors.New("wirenet: unknown codec")
+
+	// ErrListenerClosed is returned by Accept() when the listener of the named stream is closed. See Listen().
+	ErrListenerClosed = errors.New("wirenet: listener closed")
+
+	// ErrPermissionDenied is returned when the hub policy denies the stream of the other client. See WithHubPolicy().
+	// The caller receives *RemoteError with ErrorCodePermissionDenied.
+	ErrPermissionDenied = errors.New("wirenet: permission denied")
+
+	// ErrNotHub is returned when the wire is not created by Hub(). See NewHTTPIngress().
+	ErrNotHub = errors.New("wirenet: wire is not a hub")
+
+	// ErrMessageTooLarge is returned when the message is larger than MaxMessageSize. See MessageStream.
+	ErrMessageTooLarge = errors.New("wirenet: message too large")
+
+	// ErrConflictingOptions is returned by the constructors when the options can not be applied together,
+	// e.g. WithProxy() with WithTransport() or WithDialer(), WithSocketFileMode() with WithListener().
+	ErrConflictingOptions = errors.New("wirenet: conflicting options")
+
+	// ErrUnknownSession is returned by Call(), CallStream(), CallBidiStream(), ForwardLocal() and ForwardRemote()
+	// when the session is not opened by the wire.
+	ErrUnknownSession = errors.New("wirenet: session is not opened by the wire")
 )
 
+type deadlineExceededError struct{}
+
+func (deadlineExceededError) Error() string   { return "wirenet: i/o deadline reached" }
+func (deadlineExceededError) Timeout() bool   { return true }
+func (deadlineExceededError) Temporary() bool { return true }
+
 type OpError struct {
 	Op             string
 	SessionID      uuid.UUID
@@ -71,16 +125,58 @@ func (e *OpError) Error() string {
 	return s
 }
 
+// ErrorCode is the application error code sent to the peer by Stream.CloseWithError().
+type ErrorCode uint32
+
+const (
+	// ErrorCodeUnknown is used when the error code is not specified.
+	ErrorCodeUnknown ErrorCode = 0
+
+	// ErrorCodeInternal is used when the stream handler or the RPC method panics.
+	ErrorCodeInternal ErrorCode = 1
+
+	// ErrorCodeMethodNotFound is used when the RPC method is not registered.
+	ErrorCodeMethodNotFound ErrorCode = 2
+
+	// ErrorCodeInvalidArgument is used when the RPC request can not be decoded.
+	ErrorCodeInvalidArgument ErrorCode = 3
+
+	// ErrorCodeDeadlineExceeded is used when the deadline of the RPC call is reached.
+	ErrorCodeDeadlineExceeded ErrorCode = 4
+
+	// ErrorCodeCanceled is used when the RPC call is canceled.
+	ErrorCodeCanceled ErrorCode = 5
+
+	// ErrorCodePermissionDenied is used when the peer is not allowed to open the stream or the remote forwarding.
+	ErrorCodePermissionDenied ErrorCode = 6
+)
+
+// RemoteError is returned by the stream reader when the peer closes the stream with an error.
+// See Stream.CloseWithError().
+type RemoteError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("wirenet: remote error code %d: %s", e.Code, e.Message)
+}
+
 // ShutdownError is the error type with errors that occurred when closing a sessions.
 // The error is used only when closing a wired connection.
 type ShutdownError struct {
 	Errors []error
+
+	// Cut contains the names of the streams closed before completion by session id.
+	// See Wire.Shutdown().
+	Cut map[uuid.UUID][]string
 }
 
 // NewShutdownError constructs a new ShutdownError.
 func NewShutdownError() *ShutdownError {
 	return &ShutdownError{
 		Errors: make([]error, 0, 8),
+		Cut:    make(map[uuid.UUID][]string),
 	}
 }
 
@@ -89,21 +185,34 @@ func (e *ShutdownError) Add(er error) {
 	e.Errors = append(e.Errors, er)
 }
 
+// AddCut adds the names of the streams closed before completion in the session.
+func (e *ShutdownError) AddCut(sid uuid.UUID, streamNames []string) {
+	e.Cut[sid] = append(e.Cut[sid], streamNames...)
+}
+
 // IsFilled returns a true flag if the container is full, otherwise returns a false flag.
 func (e *ShutdownError) IsFilled() bool {
 	return len(e.Errors) > 0
 }
 
+// IsCut returns a true flag if some streams were closed before completion, otherwise returns a false flag.
+func (e *ShutdownError) IsCut() bool {
+	return len(e.Cut) > 0
+}
+
 // Error returns a list of all errors in a string representation.
 // Each error is separated by a symbol \n.
 func (e *ShutdownError) Error() string {
 	el := len(e.Errors)
-	if el == 1 {
+	if el == 1 && !e.IsCut() {
 		return e.Errors[0].Error()
 	}
 	buf := bytes.NewBuffer(nil)
 	for i := 0; i < el; i++ {
 		buf.WriteString("session error " + e.Errors[i].Error() + "\n")
 	}
+	for sid, streamNames := range e.Cut {
+		buf.WriteString("session " + sid.String() + " cut streams " + strings.Join(streamNames, ", ") + "\n")
+	}
 	return buf.String()
 }
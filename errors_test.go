@@ -80,3 +80,20 @@ func TestOpError_Error(t *testing.T) {
 	}
 	assert.Equal(t, "read stream sid-884a62fa-2e59-47c4-9238-ec46ec43be94 id-id ?30: some error", err.Error())
 }
+
+func TestShutdownError_AddCut(t *testing.T) {
+	uid, _ := uuid.Parse("884a62fa-2e59-47c4-9238-ec46ec43be94")
+	err := NewShutdownError()
+	assert.False(t, err.IsCut())
+	err.AddCut(uid, []string{"one"})
+	err.AddCut(uid, []string{"two"})
+	assert.True(t, err.IsCut())
+	assert.False(t, err.IsFilled())
+	assert.Equal(t, []string{"one", "two"}, err.Cut[uid])
+	assert.Equal(t, "session 884a62fa-2e59-47c4-9238-ec46ec43be94 cut streams one, two\n", err.Error())
+}
+
+func TestRemoteError_Error(t *testing.T) {
+	err := &RemoteError{Code: 404, Message: "file not found"}
+	assert.Equal(t, "wirenet: remote error code 404: file not found", err.Error())
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"time"
@@ -28,19 +27,18 @@ func main() {
 	}
 
 	wire.Stream("google:quotes", func(ctx context.Context, stream wirenet.Stream) {
-		writer := stream.Writer()
+		messages := wirenet.NewMessageStream(stream, wirenet.JSONCodec)
 		for {
 			wait()
 
-			if err := json.NewEncoder(writer).Encode(Quote{
+			if err := messages.Send(Quote{
 				Price:     rand.Intn(10000),
 				Timestamp: time.Now().Unix(),
 				Symbol:    "BTC",
 			}); err != nil {
-				fmt.Printf("[ERROR] json encode error %v", err)
+				fmt.Printf("[ERROR] send error %v", err)
 				return
 			}
-			writer.Close()
 		}
 	})
 
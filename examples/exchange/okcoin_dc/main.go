@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"time"
@@ -28,19 +27,18 @@ func main() {
 	}
 
 	wire.Stream("okcoin:quotes", func(ctx context.Context, stream wirenet.Stream) {
-		writer := stream.Writer()
+		messages := wirenet.NewMessageStream(stream, wirenet.JSONCodec)
 		for {
 			wait()
 
-			if err := json.NewEncoder(writer).Encode(Quote{
+			if err := messages.Send(Quote{
 				Price:     rand.Intn(100),
 				Timestamp: time.Now().Unix(),
 				Symbol:    "EUR",
 			}); err != nil {
-				fmt.Printf("[ERROR] json encode error %v", err)
+				fmt.Printf("[ERROR] send error %v", err)
 				return
 			}
-			writer.Close()
 		}
 	})
 
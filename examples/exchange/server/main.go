@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -65,18 +64,18 @@ func read(streamName string, session wirenet.Session) {
 			streamName, err)
 	}
 	var quote Quote
-	reader := stream.Reader()
+	messages := wirenet.NewMessageStream(stream, wirenet.JSONCodec)
 	for {
-		if err := json.NewDecoder(reader).Decode(&quote); err != nil {
+		if err := messages.Recv(&quote); err != nil {
 			if err != io.EOF {
-				fmt.Printf("[ERROR] json decode %v", err)
+				fmt.Printf("[ERROR] recv %v", err)
 			}
 			break
 		}
 		fmt.Printf("Quote:  exchange=%s, price=%d, symbol=%s, ts=%d\n",
 			streamName, quote.Price, quote.Symbol, quote.Timestamp)
 	}
-	reader.Close()
+	messages.Close()
 	if err := session.Close(); err != nil {
 		log.Println("close session error", err)
 	}
@@ -0,0 +1,231 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ForwardStreamName is the name of the stream used by ForwardRemote() to ask the peer to listen.
+// The peer allows the remote forwarding with WithRemoteForwarding().
+const ForwardStreamName = "wirenet.forward"
+
+const (
+	addrKey   = "addr"
+	streamKey = "stream"
+)
+
+// ForwardValidator is used to allow the address of the remote forwarding requested by the peer.
+// See WithRemoteForwarding().
+type ForwardValidator func(id Identification, addr string) error
+
+// Forwarding is the port forwarding started by ForwardLocal() or ForwardRemote().
+// The forwarding is stopped when the session is closed.
+type Forwarding interface {
+
+	// Addr returns the listening address, for the remote forwarding it is the address on the peer side.
+	Addr() string
+
+	// Close stops listening, the forwarded connections are not closed.
+	Close() error
+}
+
+type forwarding struct {
+	addr  string
+	stop  func() error
+	once  sync.Once
+	close error
+}
+
+func (f *forwarding) Addr() string {
+	return f.addr
+}
+
+func (f *forwarding) Close() error {
+	f.once.Do(func() {
+		f.close = f.stop()
+	})
+	return f.close
+}
+
+// ForwardHandler returns the handler which dials the target address and pipes the stream to the connection.
+// The handler is registered on the peer side of ForwardLocal(), like ssh -L.
+func ForwardHandler(targetAddr string) Handler {
+	return func(ctx context.Context, s Stream) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", targetAddr)
+		if err != nil {
+			_ = s.CloseWithError(ErrorCodeUnknown, err.Error())
+			return
+		}
+		Relay(s.Conn(), conn)
+	}
+}
+
+// ForwardLocal listens on the local address and pipes each accepted connection to the named stream
+// of the session, like ssh -L. The peer dials the target address with ForwardHandler() registered for the stream name.
+func ForwardLocal(sess Session, localAddr, streamName string) (Forwarding, error) {
+	s, err := wireSession(sess)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, err
+	}
+	f := &forwarding{
+		addr: listener.Addr().String(),
+		stop: listener.Close,
+	}
+	go s.serveForward(listener, streamName)
+	go func() {
+		<-s.done
+		_ = f.Close()
+	}()
+	return f, nil
+}
+
+// ForwardRemote asks the peer of the session to listen on the remote address and pipes each connection
+// accepted by the peer to the target address on this side, like ssh -R. The peer allows it with WithRemoteForwarding().
+func ForwardRemote(sess Session, remoteAddr, targetAddr string) (Forwarding, error) {
+	s, err := wireSession(sess)
+	if err != nil {
+		return nil, err
+	}
+	// the connections accepted by the peer are opened as the streams with the unique name
+	name := ForwardStreamName + "." + uuid.New().String()
+	s.w.Stream(name, ForwardHandler(targetAddr))
+
+	ctl, err := s.OpenStreamContext(context.Background(), ForwardStreamName,
+		WithStreamMetadata(Metadata{addrKey: remoteAddr, streamKey: name}))
+	if err != nil {
+		s.w.removeStream(name)
+		return nil, err
+	}
+	_ = ctl.SetReadDeadline(time.Time{})
+	var addr string
+	if err := NewMessageStream(ctl, JSONCodec).Recv(&addr); err != nil {
+		_ = ctl.Close()
+		s.w.removeStream(name)
+		return nil, err
+	}
+
+	f := &forwarding{
+		addr: addr,
+		stop: func() error {
+			s.w.removeStream(name)
+			return ctl.Close()
+		},
+	}
+	// the peer closes the control stream when its session is closing
+	broken := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, ctl.Reader())
+		close(broken)
+		_ = f.Close()
+	}()
+	go func() {
+		select {
+		case <-s.done:
+			_ = f.Close()
+		case <-broken:
+		}
+	}()
+	return f, nil
+}
+
+// serveForward opens the named stream for each accepted connection until the listener is closed.
+func (s *session) serveForward(listener net.Listener, streamName string) {
+	dial := DialStream(s, streamName)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			stream, err := dial(context.Background(), "")
+			if err != nil {
+				_ = conn.Close()
+				s.errLog(context.Background(), err, "forward connection")
+				return
+			}
+			Relay(conn, stream)
+		}()
+	}
+}
+
+// serveRemoteForward listens on the address requested by the peer until the control stream is closed.
+func (w *wire) serveRemoteForward(ctx context.Context, s Stream) {
+	md := s.Metadata()
+	addr := md.Get(addrKey)
+	sess := s.Session().(*session)
+	if err := w.verifyForward(sess.Identification(), addr); err != nil {
+		_ = s.CloseWithError(ErrorCodePermissionDenied, err.Error())
+		return
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = s.CloseWithError(ErrorCodeUnknown, err.Error())
+		return
+	}
+	defer listener.Close()
+
+	_ = s.SetReadDeadline(time.Time{})
+	if err := NewMessageStream(s, JSONCodec).Send(listener.Addr().String()); err != nil {
+		return
+	}
+	go sess.serveForward(listener, md.Get(streamKey))
+	go func() {
+		select {
+		case <-sess.done:
+			_ = s.Close()
+		case <-ctx.Done():
+		}
+	}()
+
+	// the requester does not write to the control stream, so the read ends when the stream is closed
+	_, _ = io.Copy(ioutil.Discard, s.Reader())
+}
+
+func (w *wire) verifyForward(id Identification, addr string) error {
+	w.mu.RLock()
+	verify := w.forwardValidator
+	w.mu.RUnlock()
+	if verify == nil {
+		return nil
+	}
+	return verify(id, addr)
+}
+
+func (w *wire) removeStream(name string) {
+	w.mu.Lock()
+	delete(w.handlers, name)
+	w.mu.Unlock()
+}
+
+// Relay pipes the connections in both directions until both of them are done, then closes them.
+// The end of one direction is sent as the half-close with CloseWrite() if the connection supports it,
+// like the net.Conn of the stream. See Stream.Conn().
+func Relay(a, b net.Conn) {
+	var wg sync.WaitGroup
+	copyHalf := func(dst, src net.Conn) {
+		defer wg.Done()
+		_ = pipe(src, dst)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+	}
+	wg.Add(2)
+	go copyHalf(a, b)
+	go copyHalf(b, a)
+	wg.Wait()
+	_ = a.Close()
+	_ = b.Close()
+}
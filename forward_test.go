@@ -0,0 +1,113 @@
+package wirenet
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upperServer returns the address of the TCP server which upper-cases the lines until the connection is closed.
+func upperServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write([]byte(strings.ToUpper(line)))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), func() {
+		_ = listener.Close()
+	}
+}
+
+// assertForwarded asserts that the connection to the address is forwarded to the upper server.
+func assertForwarded(t *testing.T, addr string) {
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for _, msg := range []string{"ping\n", "pong\n"} {
+		_, err = conn.Write([]byte(msg))
+		assert.Nil(t, err)
+		line, err := rd.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ToUpper(msg), line)
+	}
+	// the half-close is forwarded, the target closes the connection
+	assert.Nil(t, conn.(*net.TCPConn).CloseWrite())
+	rest, err := ioutil.ReadAll(rd)
+	assert.Nil(t, err)
+	assert.Empty(t, rest)
+}
+
+func TestForwardLocal(t *testing.T) {
+	target, closeTarget := upperServer(t)
+	defer closeTarget()
+
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"upper": ForwardHandler(target),
+	})
+	defer closeWires()
+
+	fwd, err := ForwardLocal(sess, "127.0.0.1:0", "upper")
+	assert.Nil(t, err)
+	assertForwarded(t, fwd.Addr())
+	assertForwarded(t, fwd.Addr())
+
+	assert.Nil(t, fwd.Close())
+	_, err = net.Dial("tcp", fwd.Addr())
+	assert.NotNil(t, err)
+}
+
+func TestForwardRemote(t *testing.T) {
+	target, closeTarget := upperServer(t)
+	defer closeTarget()
+
+	sess, closeWires := connectStreams(t, nil, WithRemoteForwarding(func(id Identification, addr string) error {
+		if !strings.HasPrefix(addr, "127.0.0.1:") {
+			return errors.New("address not allowed")
+		}
+		return nil
+	}))
+	defer closeWires()
+
+	_, err := ForwardRemote(sess, "0.0.0.0:0", target)
+	assert.Equal(t, &RemoteError{Code: ErrorCodePermissionDenied, Message: "address not allowed"}, err)
+
+	// the peer side listens, the connections are forwarded to the target of this side
+	fwd, err := ForwardRemote(sess, "127.0.0.1:0", target)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "127.0.0.1:0", fwd.Addr())
+	assertForwarded(t, fwd.Addr())
+	assertForwarded(t, fwd.Addr())
+
+	// the peer stops listening when the forwarding is closed
+	assert.Nil(t, fwd.Close())
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", fwd.Addr())
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
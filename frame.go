@@ -15,6 +15,8 @@ const (
 
 	openSessTyp  uint32 = 0x32
 	confSessType uint32 = 0x64
+	metaFrameTyp uint32 = 0x128
+	denyFrameTyp uint32 = 0x256
 
 	hdrLen       = 4
 	headerLength = hdrLen * 3
@@ -38,6 +40,14 @@ func (f frame) IsPermFrame() bool {
 	return f.Type() == permFrameTyp
 }
 
+func (f frame) IsMetaFrame() bool {
+	return f.Type() == metaFrameTyp
+}
+
+func (f frame) IsDenyFrame() bool {
+	return f.Type() == denyFrameTyp
+}
+
 func (f frame) Type() uint32 {
 	return binary.LittleEndian.Uint32(f[0:4])
 }
@@ -165,6 +175,9 @@ func sendFrame(name string, typ uint32, data []byte, rw io.ReadWriter) (frame, e
 	if err != nil {
 		return nil, err
 	}
+	if frm.IsDenyFrame() {
+		return nil, &RemoteError{Code: ErrorCodePermissionDenied, Message: string(frm.Payload())}
+	}
 	if frm.IsErrFrame() || frm.IsPermFrame() {
 		return nil, errors.New(string(frm.Payload()))
 	}
@@ -183,6 +196,9 @@ func recvFrame(rw io.ReadWriter, fn func(frame) error) (frm frame, err error) {
 	if fn != nil {
 		if checkErr := fn(frm); checkErr != nil {
 			frameTyp = errFrameTyp
+			if errors.Is(checkErr, ErrPermissionDenied) {
+				frameTyp = denyFrameTyp
+			}
 			frameErr = []byte(checkErr.Error())
 			err = checkErr
 		}
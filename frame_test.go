@@ -60,6 +60,9 @@ func TestFrame_Is(t *testing.T) {
 		{
 			typ: recvFrameTyp,
 		},
+		{
+			typ: denyFrameTyp,
+		},
 	}
 	for _, c := range tests {
 		err := newEncoder(buf).Encode(c.typ, cmd, payload)
@@ -74,6 +77,8 @@ func TestFrame_Is(t *testing.T) {
 			assert.True(t, frm.IsPermFrame())
 		case initFrameTyp:
 			assert.True(t, frm.IsInitFrame())
+		case denyFrameTyp:
+			assert.True(t, frm.IsDenyFrame())
 		}
 		buf.Reset()
 	}
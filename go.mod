@@ -8,6 +8,7 @@ require (
 	github.com/golang/mock v1.4.3
 	github.com/golang/protobuf v1.4.0
 	github.com/google/uuid v1.1.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/hashicorp/yamux v0.0.0-20190923154419-df201c70410d
 	github.com/magiconair/properties v1.8.1 // indirect
 	github.com/stretchr/testify v1.5.1
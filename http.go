@@ -0,0 +1,32 @@
+package wirenet
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPIdleConnTimeout is the idle timeout of the keep-alive connections of NewHTTPTransport().
+const DefaultHTTPIdleConnTimeout = 90 * time.Second
+
+// NewHTTPTransport returns the http.RoundTripper which sends the requests over the named streams of the session.
+// The peer serves the requests by http.Server with Listen(). The host of the request URL is not used for dialing.
+// The keep-alive connections are reused by the requests and closed when the session is closed.
+func NewHTTPTransport(sess Session, streamName string) *http.Transport {
+	dial := DialStream(sess, streamName)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(ctx, addr)
+		},
+		IdleConnTimeout: DefaultHTTPIdleConnTimeout,
+	}
+	if s, ok := sess.(*session); ok {
+		// the idle connections are active streams, so the session would wait for them on close
+		go func() {
+			<-s.done
+			transport.CloseIdleConnections()
+		}()
+	}
+	return transport
+}
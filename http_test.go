@@ -0,0 +1,91 @@
+package wirenet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPTransport(t *testing.T) {
+	var listener net.Listener
+	sess, closeWires := connectStreams(t, nil, func(w *wire) {
+		listener = Listen(w, "http")
+	})
+
+	var conns int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		_, _ = w.Write(body)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "event %d\n", i)
+			flusher.Flush()
+		}
+	})
+	srv := &http.Server{
+		Handler: mux,
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		},
+	}
+	served := make(chan error)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+
+	client := &http.Client{Transport: NewHTTPTransport(sess, "http")}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Post("http://wirenet/echo", "text/plain", strings.NewReader("hello"))
+		assert.Nil(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, resp.Body.Close())
+		assert.Equal(t, "hello", string(body))
+	}
+	// the keep-alive connection is reused
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+
+	// the streaming request and response bodies
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(pw, "part %d;", i)
+		}
+		_ = pw.Close()
+	}()
+	resp, err := client.Post("http://wirenet/echo", "text/plain", pr)
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Body.Close())
+	assert.Equal(t, "part 0;part 1;part 2;", string(body))
+
+	resp, err = client.Get("http://wirenet/events")
+	assert.Nil(t, err)
+	rd := bufio.NewReader(resp.Body)
+	for i := 0; i < 3; i++ {
+		line, err := rd.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("event %d\n", i), line)
+	}
+	assert.Nil(t, resp.Body.Close())
+
+	// the idle connections are closed with the session
+	closeWires()
+	assert.Nil(t, srv.Close())
+	assert.Equal(t, http.ErrServerClosed, <-served)
+}
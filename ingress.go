@@ -0,0 +1,169 @@
+package wirenet
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// HTTPIngress is the http.Handler of the hub which forwards the requests to the clients over the named streams.
+// The request is routed by the host or the path prefix to the stream name registered by the client,
+// the client serves the stream with ServeHTTPTunnel(). Each request is sent over the keep-alive stream of the session.
+type HTTPIngress struct {
+	wire       *wire
+	hosts      map[string]string
+	prefixes   map[string]string
+	transports map[ingressKey]*http.Transport
+	mu         sync.RWMutex
+}
+
+type ingressKey struct {
+	sid        uuid.UUID
+	streamName string
+}
+
+// NewHTTPIngress returns the ingress of the hub. ErrNotHub is returned if the wire is not created by Hub().
+func NewHTTPIngress(w Wire) (*HTTPIngress, error) {
+	hub, ok := w.(*wire)
+	if !ok || !hub.isHubMode() {
+		return nil, ErrNotHub
+	}
+	return &HTTPIngress{
+		wire:       hub,
+		hosts:      make(map[string]string),
+		prefixes:   make(map[string]string),
+		transports: make(map[ingressKey]*http.Transport),
+	}, nil
+}
+
+// Host routes the requests with the host to the stream. The port of the host is ignored.
+// The host route takes precedence over the prefix route.
+func (i *HTTPIngress) Host(host, streamName string) {
+	i.mu.Lock()
+	i.hosts[strings.ToLower(host)] = streamName
+	i.mu.Unlock()
+}
+
+// Prefix routes the requests with the path prefix to the stream. The prefix is stripped from the path,
+// the longest prefix is matched.
+func (i *HTTPIngress) Prefix(prefix, streamName string) {
+	i.mu.Lock()
+	i.prefixes[strings.TrimSuffix(prefix, "/")] = streamName
+	i.mu.Unlock()
+}
+
+func (i *HTTPIngress) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	streamName, prefix, ok := i.route(r)
+	if !ok {
+		http.NotFound(rw, r)
+		return
+	}
+	// the address of the caller is the balance key, see ConsistentHashBalancer()
+	key := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(key); err == nil {
+		key = host
+	}
+	sess, err := i.wire.findSession(nil, streamName, key)
+	if err != nil {
+		http.Error(rw, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = req.Host
+			req.URL.Path = stripPrefix(req.URL.Path, prefix)
+			if len(req.URL.RawPath) > 0 {
+				req.URL.RawPath = stripPrefix(req.URL.RawPath, prefix)
+			}
+			req.Header.Set("X-Forwarded-Host", req.Host)
+			if req.TLS != nil {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			} else {
+				req.Header.Set("X-Forwarded-Proto", "http")
+			}
+		},
+		Transport: i.transport(sess, streamName),
+		ErrorHandler: func(rw http.ResponseWriter, req *http.Request, err error) {
+			sess.(*session).errLog(req.Context(), err, "http ingress")
+			rw.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(rw, r)
+}
+
+// route returns the stream name and the path prefix of the request.
+func (i *HTTPIngress) route(r *http.Request) (streamName, prefix string, ok bool) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if streamName, ok = i.hosts[strings.ToLower(host)]; ok {
+		return streamName, "", true
+	}
+	for p, name := range i.prefixes {
+		if hasPathPrefix(r.URL.Path, p) && (!ok || len(p) > len(prefix)) {
+			streamName, prefix, ok = name, p, true
+		}
+	}
+	return streamName, prefix, ok
+}
+
+// transport returns the keep-alive transport of the stream, the transport is removed when the session is closed.
+func (i *HTTPIngress) transport(sess Session, streamName string) *http.Transport {
+	key := ingressKey{sid: sess.ID(), streamName: streamName}
+	i.mu.RLock()
+	transport, ok := i.transports[key]
+	i.mu.RUnlock()
+	if ok {
+		return transport
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if transport, ok = i.transports[key]; ok {
+		return transport
+	}
+	transport = NewHTTPTransport(sess, streamName)
+	i.transports[key] = transport
+	go func() {
+		<-sess.(*session).done
+		i.mu.Lock()
+		delete(i.transports, key)
+		i.mu.Unlock()
+	}()
+	return transport
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func stripPrefix(path, prefix string) string {
+	path = strings.TrimPrefix(path, prefix)
+	if len(path) == 0 {
+		return "/"
+	}
+	return path
+}
+
+// ServeHTTPTunnel serves the requests of the hub ingress on the client side, see HTTPIngress.
+// The requests are proxied to the target, usually the local HTTP service.
+// The listener of the stream is registered on the wire, so the tunnel is served before Connect().
+func ServeHTTPTunnel(w Wire, streamName string, target *url.URL) io.Closer {
+	listener := Listen(w, streamName)
+	srv := &http.Server{Handler: httputil.NewSingleHostReverseProxy(target)}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	return srv
+}
@@ -0,0 +1,104 @@
+package wirenet
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPIngress(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	initSess := make(chan struct{})
+
+	// the local service of the client
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+" "+r.URL.Path+" "+r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer service.Close()
+	target, err := url.Parse(service.URL)
+	assert.Nil(t, err)
+
+	// hub
+	hub, err := Hub(addr,
+		WithConnectHook(func(closer io.Closer) {
+			close(initHub)
+		}),
+		WithSessionOpenHook(func(s Session) {
+			close(initSess)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	ingress, err := NewHTTPIngress(hub)
+	assert.Nil(t, err)
+	ingress.Host("app.example.com", "app")
+	ingress.Prefix("/app/", "app")
+	ingress.Prefix("/app/missing", "missing")
+	srv := httptest.NewServer(ingress)
+	defer srv.Close()
+
+	// client
+	client, err := Join(addr)
+	assert.Nil(t, err)
+	tunnel := ServeHTTPTunnel(client, "app", target)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initSess
+
+	get := func(host, path string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		assert.Nil(t, err)
+		if len(host) > 0 {
+			req.Host = host
+		}
+		resp, err := srv.Client().Do(req)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("app.example.com:8080", "/status")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "app.example.com:8080 /status app.example.com:8080", body)
+
+	code, body = get("", "/app/status")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, " /status ")
+
+	code, body = get("", "/app")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, " / ")
+
+	code, _ = get("", "/apple")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// the stream is not registered by the clients
+	code, _ = get("", "/app/missing/status")
+	assert.Equal(t, http.StatusBadGateway, code)
+
+	assert.Nil(t, tunnel.Close())
+	assert.Nil(t, client.Close())
+	assert.Nil(t, hub.Close())
+}
+
+func TestNewHTTPIngress_NotHub(t *testing.T) {
+	server, err := Mount(genAddr(t))
+	assert.Nil(t, err)
+	_, err = NewHTTPIngress(server)
+	assert.Equal(t, ErrNotHub, err)
+
+	_, err = NewHTTPIngress(nil)
+	assert.Equal(t, ErrNotHub, err)
+}
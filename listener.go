@@ -0,0 +1,83 @@
+package wirenet
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Listen returns the listener of the named stream, each stream opened by the peer is accepted as net.Conn.
+// The handler of the stream is registered on the wire, so the listener is created before Connect().
+// The listener can be served by grpc.Server, http.Server, etc. from both the server side and the client side.
+func Listen(w Wire, streamName string) net.Listener {
+	l := &streamListener{
+		addr:  streamAddr(streamName),
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+	w.Stream(streamName, l.handle)
+	return l
+}
+
+// DialStream returns the dial function which opens the named stream on the session and returns it as net.Conn.
+// The function is used with grpc.WithContextDialer(), the address is ignored.
+func DialStream(sess Session, streamName string) func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, _ string) (net.Conn, error) {
+		s, err := sess.OpenStreamContext(ctx, streamName)
+		if err != nil {
+			return nil, err
+		}
+		return s.Conn(), nil
+	}
+}
+
+type streamListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// handle hands the stream over to Accept() and waits until the connection is closed.
+func (l *streamListener) handle(ctx context.Context, s Stream) {
+	select {
+	case l.conns <- s.Conn():
+	case <-l.done:
+		return
+	case <-ctx.Done():
+		return
+	}
+	<-ctx.Done()
+}
+
+func (l *streamListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close stops accepting the streams, the accepted connections are not closed.
+func (l *streamListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *streamListener) Addr() net.Addr {
+	return l.addr
+}
+
+// streamAddr is the address of the named stream listener.
+type streamAddr string
+
+func (a streamAddr) Network() string {
+	return "wirenet"
+}
+
+func (a streamAddr) String() string {
+	return string(a)
+}
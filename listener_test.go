@@ -0,0 +1,102 @@
+package wirenet
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen_DialStream(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initSess := make(chan Session, 1)
+	timeouts := WithReadWriteTimeouts(100*time.Millisecond, time.Second)
+
+	// server side
+	server, err := Mount(addr, timeouts,
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}),
+		WithSessionOpenHook(func(s Session) {
+			initSess <- s
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side behind NAT serves the connections of the server
+	client, err := Join(addr, timeouts)
+	assert.Nil(t, err)
+	listener := Listen(client, "upper")
+	assert.Equal(t, "wirenet", listener.Addr().Network())
+	assert.Equal(t, "upper", listener.Addr().String())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				assert.Equal(t, ErrListenerClosed, err)
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if _, err := conn.Write([]byte(strings.ToUpper(line))); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initSess
+
+	dial := DialStream(sess, "upper")
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dial(context.Background(), "")
+			assert.Nil(t, err)
+			rd := bufio.NewReader(conn)
+			for _, msg := range []string{"ping\n", "pong\n"} {
+				_, err = conn.Write([]byte(msg))
+				assert.Nil(t, err)
+				line, err := rd.ReadString('\n')
+				assert.Nil(t, err)
+				assert.Equal(t, strings.ToUpper(msg), line)
+				// the idle connection is not closed by the read timeout
+				time.Sleep(300 * time.Millisecond)
+			}
+			assert.Nil(t, conn.Close())
+		}()
+	}
+	wg.Wait()
+
+	assert.Nil(t, listener.Close())
+	<-served
+
+	_, err = DialStream(sess, "unknown")(context.Background(), "")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
@@ -0,0 +1,120 @@
+package wirenet
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// MaxMessageSize is the maximum size of the encoded message of the MessageStream.
+const MaxMessageSize = 64 << 20
+
+// MessageStream sends and receives the typed messages over the named stream.
+// Each message is framed by its size, so the messages are never mixed up
+// and the writer of the stream is not closed after each message.
+type MessageStream interface {
+
+	// Stream returns the named stream of the message stream.
+	Stream() Stream
+
+	// Codec returns the codec of the messages.
+	Codec() Codec
+
+	// Send encodes the message and sends it to the peer.
+	Send(v interface{}) error
+
+	// Recv receives the message and decodes it to the value pointed to by v.
+	// Returns io.EOF when the peer calls CloseSend(),
+	// or *RemoteError when the peer closes the stream with an error.
+	Recv(v interface{}) error
+
+	// CloseSend closes the sending side, the peer receives io.EOF.
+	CloseSend() error
+
+	// Close closes the named stream.
+	Close() error
+}
+
+type messageStream struct {
+	stream Stream
+	codec  Codec
+	reader io.ReadCloser
+	writer io.WriteCloser
+	hdr    []byte
+	rmu    sync.Mutex
+	wmu    sync.Mutex
+}
+
+// NewMessageStream returns the message stream over the named stream with the codec.
+// If the codec is nil, JSONCodec is used.
+func NewMessageStream(stream Stream, codec Codec) MessageStream {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &messageStream{
+		stream: stream,
+		codec:  codec,
+		reader: stream.Reader(),
+		writer: stream.Writer(),
+		hdr:    make([]byte, hdrLen),
+	}
+}
+
+func (m *messageStream) Stream() Stream {
+	return m.stream
+}
+
+func (m *messageStream) Codec() Codec {
+	return m.codec
+}
+
+func (m *messageStream) Send(v interface{}) error {
+	data, err := m.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	// the size and the message are sent in one chunk
+	msg := make([]byte, hdrLen+len(data))
+	binary.LittleEndian.PutUint32(msg, uint32(len(data)))
+	copy(msg[hdrLen:], data)
+
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	_, err = m.writer.Write(msg)
+	return err
+}
+
+func (m *messageStream) Recv(v interface{}) error {
+	m.rmu.Lock()
+	defer m.rmu.Unlock()
+
+	if _, err := io.ReadFull(m.reader, m.hdr); err != nil {
+		return err
+	}
+	size := binary.LittleEndian.Uint32(m.hdr)
+	if size > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(m.reader, data); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return m.codec.Unmarshal(data, v)
+}
+
+func (m *messageStream) CloseSend() error {
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	return m.writer.Close()
+}
+
+func (m *messageStream) Close() error {
+	return m.stream.Close()
+}
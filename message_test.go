@@ -0,0 +1,86 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/mediabuyerbot/go-wirenet/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageStream_SendRecv(t *testing.T) {
+	echo := func(codec Codec, newMsg func() interface{}) Handler {
+		return func(ctx context.Context, stream Stream) {
+			ms := NewMessageStream(stream, codec)
+			for {
+				msg := newMsg()
+				if err := ms.Recv(msg); err != nil {
+					assert.Equal(t, io.EOF, err)
+					break
+				}
+				assert.Nil(t, ms.Send(msg))
+			}
+			assert.Nil(t, ms.CloseSend())
+		}
+	}
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"json":  echo(JSONCodec, func() interface{} { return new(testMessage) }),
+		"gob":   echo(GobCodec, func() interface{} { return new(testMessage) }),
+		"proto": echo(ProtoCodec, func() interface{} { return new(wirenettest.Frame) }),
+	})
+	defer closeWires()
+
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		stream, err := sess.OpenStream(codec.Name())
+		assert.Nil(t, err)
+		ms := NewMessageStream(stream, codec)
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, ms.Send(&testMessage{Name: codec.Name(), Value: i}))
+		}
+		assert.Nil(t, ms.CloseSend())
+		for i := 0; i < 3; i++ {
+			var msg testMessage
+			assert.Nil(t, ms.Recv(&msg))
+			assert.Equal(t, testMessage{Name: codec.Name(), Value: i}, msg)
+		}
+		assert.Equal(t, io.EOF, ms.Recv(new(testMessage)))
+		assert.Nil(t, ms.Close())
+	}
+
+	// the empty message is received
+	stream, err := sess.OpenStream("proto")
+	assert.Nil(t, err)
+	ms := NewMessageStream(stream, ProtoCodec)
+	assert.Nil(t, ms.Send(&wirenettest.Frame{}))
+	assert.Nil(t, ms.Send(&wirenettest.Frame{Cmd: "test"}))
+	assert.Nil(t, ms.CloseSend())
+	var frm wirenettest.Frame
+	assert.Nil(t, ms.Recv(&frm))
+	assert.Equal(t, "", frm.Cmd)
+	assert.Nil(t, ms.Recv(&frm))
+	assert.Equal(t, "test", frm.Cmd)
+	assert.Equal(t, io.EOF, ms.Recv(&frm))
+	assert.Nil(t, ms.Close())
+}
+
+func TestMessageStream_RemoteError(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"reject": func(ctx context.Context, stream Stream) {
+			ms := NewMessageStream(stream, nil)
+			assert.Nil(t, ms.Send(testMessage{Name: "first"}))
+			assert.Nil(t, stream.CloseWithError(403, "forbidden"))
+		},
+	})
+	defer closeWires()
+
+	stream, err := sess.OpenStream("reject")
+	assert.Nil(t, err)
+	ms := NewMessageStream(stream, nil)
+	assert.Equal(t, JSONCodec, ms.Codec())
+	var msg testMessage
+	assert.Nil(t, ms.Recv(&msg))
+	assert.Equal(t, "first", msg.Name)
+	assert.Equal(t, &RemoteError{Code: 403, Message: "forbidden"}, ms.Recv(&msg))
+	assert.Nil(t, ms.Close())
+}
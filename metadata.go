@@ -0,0 +1,104 @@
+package wirenet
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/yamux"
+	"github.com/mediabuyerbot/go-wirenet/pb"
+)
+
+// Metadata is the key/value headers of the stream (request id, content type, caller info, etc.).
+// The metadata is sent by OpenStreamContext() and delivered to the handler by Stream.Metadata().
+type Metadata map[string]string
+
+// Get returns the value of the key or an empty string.
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+// StreamOption is used to configure the stream opening. See OpenStreamContext().
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	metadata Metadata
+}
+
+// WithStreamMetadata adds the key/value headers sent to the handler of the stream.
+func WithStreamMetadata(md Metadata) StreamOption {
+	return func(o *streamOptions) {
+		if o.metadata == nil {
+			o.metadata = make(Metadata, len(md))
+		}
+		for k, v := range md {
+			o.metadata[k] = v
+		}
+	}
+}
+
+// streamRequest returns the token and the metadata of the stream opening frame.
+func streamRequest(frm frame) (Token, Metadata, error) {
+	if !frm.IsMetaFrame() {
+		return frm.Payload(), nil, nil
+	}
+	var req pb.OpenStreamRequest
+	if err := proto.Unmarshal(frm.Payload(), &req); err != nil {
+		return nil, nil, err
+	}
+	return req.Token, req.Metadata, nil
+}
+
+// streamFrame returns the type and the payload of the stream opening frame.
+// The metadata frame is used only if the metadata is not empty.
+func streamFrame(token Token, md Metadata) (uint32, []byte, error) {
+	if len(md) == 0 {
+		return permFrameTyp, token, nil
+	}
+	payload, err := proto.Marshal(&pb.OpenStreamRequest{
+		Token:    token,
+		Metadata: md,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return metaFrameTyp, payload, nil
+}
+
+// deadlineCloser is the yamux stream of the handshake or the stream aborted by watchContext().
+type deadlineCloser interface {
+	SetDeadline(t time.Time) error
+	Close() error
+}
+
+// watchContext aborts the handshake or the blocking read of the stream when the context is done.
+// The stop function returns the context error if the operation was aborted, otherwise the operation error.
+func watchContext(ctx context.Context, conn deadlineCloser) (stop func(err error) error) {
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		_ = conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	return func(err error) error {
+		close(done)
+		<-exited
+		_ = conn.SetDeadline(time.Time{})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		// the deadline of the stream is reached before the context
+		if (err == yamux.ErrTimeout || err == ErrDeadlineExceeded) && hasDeadline {
+			return context.DeadlineExceeded
+		}
+		return err
+	}
+}
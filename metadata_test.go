@@ -0,0 +1,128 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_OpenStreamContextMetadata(t *testing.T) {
+	received := make(chan Metadata, 2)
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"meta": func(ctx context.Context, stream Stream) {
+			received <- stream.Metadata()
+		},
+	})
+	defer closeWires()
+
+	md := Metadata{"request-id": "42", "content-type": "application/json"}
+	stream, err := sess.OpenStreamContext(context.Background(), "meta",
+		WithStreamMetadata(md),
+		WithStreamMetadata(Metadata{"caller": "test"}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "42", stream.Metadata().Get("request-id"))
+	got := <-received
+	assert.Equal(t, Metadata{
+		"request-id":   "42",
+		"content-type": "application/json",
+		"caller":       "test",
+	}, got)
+	assert.Nil(t, stream.Close())
+
+	// without metadata
+	stream, err = sess.OpenStream("meta")
+	assert.Nil(t, err)
+	assert.Len(t, <-received, 0)
+	assert.Equal(t, "", stream.Metadata().Get("request-id"))
+	assert.Nil(t, stream.Close())
+}
+
+func TestSession_OpenStreamContextAbort(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"slow": func(ctx context.Context, stream Stream) {},
+	}, WithTokenValidator(func(streamName string, id Identification, token Token) error {
+		if streamName == "slow" {
+			time.Sleep(2 * time.Second)
+		}
+		return nil
+	}))
+	defer closeWires()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	stream, err := sess.OpenStreamContext(ctx, "slow")
+	assert.Nil(t, stream)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	stream, err = sess.OpenStreamContext(ctx, "slow")
+	assert.Nil(t, stream)
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	stream, err = sess.OpenStreamContext(ctx, "slow")
+	assert.Nil(t, stream)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestHub_OpenStreamContextMetadata(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	initCli1 := make(chan struct{})
+	initCli2 := make(chan Session)
+	received := make(chan Metadata, 1)
+
+	// hub
+	hub, err := Hub(addr, WithConnectHook(func(closer io.Closer) {
+		close(initHub)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	// client1 handles the stream
+	client1, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		close(initCli1)
+	}))
+	assert.Nil(t, err)
+	client1.Stream("meta", func(ctx context.Context, stream Stream) {
+		received <- stream.Metadata()
+	})
+	go func() {
+		assert.Nil(t, client1.Connect())
+	}()
+	<-initCli1
+
+	// client2 opens the stream through the hub
+	client2, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		initCli2 <- s
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client2.Connect())
+	}()
+	sess := <-initCli2
+
+	stream, err := sess.OpenStreamContext(context.Background(), "meta",
+		WithStreamMetadata(Metadata{"request-id": "7"}))
+	assert.Nil(t, err)
+	assert.Equal(t, Metadata{"request-id": "7"}, <-received)
+	assert.Nil(t, stream.Close())
+
+	assert.Nil(t, client2.Close())
+	assert.Nil(t, client1.Close())
+	assert.Nil(t, hub.Close())
+}
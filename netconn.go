@@ -0,0 +1,142 @@
+package wirenet
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// netConn is the net.Conn over the chunks of the stream.
+// The peer can use the stream reader and writer or the net.Conn of the stream.
+type netConn struct {
+	stream *stream
+
+	rmu    sync.Mutex
+	hdr    []byte
+	hdrN   int
+	remain int
+	eof    bool
+
+	wmu    sync.Mutex
+	wclose bool
+}
+
+func newNetConn(s *stream) *netConn {
+	return &netConn{
+		stream: s,
+		hdr:    make([]byte, hdrLen),
+	}
+}
+
+func (c *netConn) Read(p []byte) (n int, err error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := c.stream.remoteError(); err != nil {
+		return 0, err
+	}
+	for c.remain == 0 {
+		if c.eof {
+			return 0, io.EOF
+		}
+		if err := c.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(p) > c.remain {
+		p = p[:c.remain]
+	}
+	n, err = c.stream.conn.Read(p)
+	c.remain -= n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return n, err
+}
+
+// readHeader reads the chunk header, the partially read header is kept until the next call.
+func (c *netConn) readHeader() error {
+	for c.hdrN < hdrLen {
+		n, err := c.stream.conn.Read(c.hdr[c.hdrN:])
+		c.hdrN += n
+		if err != nil && c.hdrN < hdrLen {
+			if err == io.EOF && c.hdrN > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	c.hdrN = 0
+	size := binary.LittleEndian.Uint32(c.hdr)
+	switch size {
+	case eof:
+		c.eof = true
+		return nil
+	case errChunk:
+		return c.stream.readRemoteError()
+	}
+	c.remain = int(size)
+	return nil
+}
+
+func (c *netConn) Write(p []byte) (n int, err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.wclose || c.stream.IsClosed() {
+		return 0, ErrStreamClosed
+	}
+	// the empty chunk is the end of the stream
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := c.stream.writeHdr(len(p)); err != nil {
+		return 0, err
+	}
+	return c.stream.conn.Write(p)
+}
+
+// CloseWrite shuts down the writing side, the peer reads io.EOF.
+func (c *netConn) CloseWrite() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.wclose {
+		return nil
+	}
+	c.wclose = true
+	return c.stream.writeEOF()
+}
+
+func (c *netConn) Close() error {
+	return c.stream.Close()
+}
+
+func (c *netConn) LocalAddr() net.Addr {
+	return c.stream.sess.connection().LocalAddr()
+}
+
+func (c *netConn) RemoteAddr() net.Addr {
+	return c.stream.sess.connection().RemoteAddr()
+}
+
+func (c *netConn) SetDeadline(t time.Time) error {
+	return c.stream.SetDeadline(t)
+}
+
+func (c *netConn) SetReadDeadline(t time.Time) error {
+	return c.stream.SetReadDeadline(t)
+}
+
+func (c *netConn) SetWriteDeadline(t time.Time) error {
+	return c.stream.SetWriteDeadline(t)
+}
@@ -0,0 +1,187 @@
+package wirenet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// connectStreams connects the client side to the server side with the stream handlers and the server options.
+func connectStreams(t *testing.T, handlers map[string]Handler, opts ...Option) (Session, func()) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+
+	// server side
+	opts = append(opts, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	server, err := Mount(addr, opts...)
+	assert.Nil(t, err)
+	for name, handler := range handlers {
+		server.Stream(name, handler)
+	}
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		initCli <- s
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	return sess, func() {
+		assert.Nil(t, client.Close())
+		assert.Nil(t, server.Close())
+	}
+}
+
+func TestStream_ConnHTTP(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"http": func(ctx context.Context, stream Stream) {
+			conn := stream.Conn()
+			req, err := http.ReadRequest(bufio.NewReader(conn))
+			assert.Nil(t, err)
+			body, err := ioutil.ReadAll(req.Body)
+			assert.Nil(t, err)
+			resp := &http.Response{
+				StatusCode:    http.StatusOK,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				ContentLength: int64(len(body)),
+				Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			}
+			assert.Nil(t, resp.Write(conn))
+		},
+	})
+	defer closeWires()
+
+	stream, err := sess.OpenStream("http")
+	assert.Nil(t, err)
+	conn := stream.Conn()
+	assert.NotNil(t, conn.LocalAddr())
+	assert.NotNil(t, conn.RemoteAddr())
+
+	req, err := http.NewRequest(http.MethodPost, "http://wirenet/echo", strings.NewReader("hello"))
+	assert.Nil(t, err)
+	assert.Nil(t, req.Write(conn))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.Nil(t, conn.Close())
+}
+
+func TestStream_ConnTLS(t *testing.T) {
+	serverTLSConf, err := LoadCertificates("server", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf, err := LoadCertificates("client", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf.InsecureSkipVerify = true
+
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"tls": func(ctx context.Context, stream Stream) {
+			conn := tls.Server(stream.Conn(), serverTLSConf)
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			assert.Nil(t, err)
+			_, err = conn.Write([]byte(strings.ToUpper(line)))
+			assert.Nil(t, err)
+			assert.Nil(t, conn.Close())
+		},
+	})
+	defer closeWires()
+
+	stream, err := sess.OpenStream("tls")
+	assert.Nil(t, err)
+	conn := tls.Client(stream.Conn(), clientTLSConf)
+	_, err = conn.Write([]byte("ping\n"))
+	assert.Nil(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "PING\n", line)
+	assert.Nil(t, conn.Close())
+}
+
+func TestStream_ConnIdleRead(t *testing.T) {
+	received := make(chan string)
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"idle": func(ctx context.Context, stream Stream) {
+			// the read timeout is disabled by the net.Conn
+			conn := stream.Conn()
+			buf := make([]byte, 4)
+			for i := 0; i < 2; i++ {
+				n, err := conn.Read(buf)
+				assert.Nil(t, err)
+				received <- string(buf[:n])
+				assert.Nil(t, conn.SetReadDeadline(time.Time{}))
+			}
+		},
+	}, WithReadWriteTimeouts(200*time.Millisecond, time.Second))
+	defer closeWires()
+
+	stream, err := sess.OpenStream("idle")
+	assert.Nil(t, err)
+	conn := stream.Conn()
+	for _, msg := range []string{"ping", "pong"} {
+		time.Sleep(500 * time.Millisecond)
+		_, err = conn.Write([]byte(msg))
+		assert.Nil(t, err)
+		assert.Equal(t, msg, <-received)
+	}
+	assert.Nil(t, conn.Close())
+}
+
+func TestStream_ConnCloseWrite(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"upper": func(ctx context.Context, stream Stream) {
+			// the chunks written by the net.Conn are read by the stream reader
+			buf := bytes.NewBuffer(nil)
+			_, err := stream.WriteTo(buf)
+			assert.Nil(t, err)
+			_, err = stream.ReadFrom(strings.NewReader(strings.ToUpper(buf.String())))
+			assert.Nil(t, err)
+		},
+	})
+	defer closeWires()
+
+	stream, err := sess.OpenStream("upper")
+	assert.Nil(t, err)
+	conn := stream.Conn()
+	for _, part := range []string{"a", "", "bc", "def"} {
+		_, err := conn.Write([]byte(part))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, conn.(interface{ CloseWrite() error }).CloseWrite())
+	_, err = conn.Write([]byte("g"))
+	assert.Equal(t, ErrStreamClosed, err)
+
+	// small reads return the data of the chunk
+	buf := make([]byte, 2)
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "AB", string(buf[:n]))
+	rest, err := ioutil.ReadAll(conn)
+	assert.Nil(t, err)
+	assert.Equal(t, "CDEF", string(rest))
+
+	n, err = conn.Read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+	assert.Nil(t, conn.Close())
+}
@@ -4,6 +4,9 @@ import (
 	"crypto/tls"
 	"io"
 	"math"
+	"net"
+	"net/url"
+	"os"
 	"time"
 )
 
@@ -17,6 +20,7 @@ const (
 	DefaultRetryMax            = 100
 	DefaultRetryWaitMax        = 60 * time.Second
 	DefaultRetryWaitMin        = 5 * time.Second
+	DefaultDialTimeout         = 10 * time.Second
 )
 
 type (
@@ -31,6 +35,13 @@ func WithConnectHook(hook func(io.Closer)) Option {
 	}
 }
 
+// WithStateHook sets the hook called when the connection state of the wire changes.
+func WithStateHook(hook StateHook) Option {
+	return func(w *wire) {
+		w.stateHook = hook
+	}
+}
+
 func WithErrorHandler(h ErrorHandler) Option {
 	return func(w *wire) {
 		w.errorHandler = h
@@ -62,12 +73,117 @@ func WithTokenValidator(v TokenValidator) Option {
 	}
 }
 
+// WithTLS sets the TLS configuration of the connections. On the client side the server name is taken
+// from the address unless conf.ServerName is set, it is "localhost" for the Unix domain sockets.
 func WithTLS(conf *tls.Config) Option {
 	return func(w *wire) {
 		w.tlsConfig = conf
 	}
 }
 
+// WithTransport sets the transport used to listen and dial connections.
+// The default transport is TCP. The transport is responsible for the proxy and the socket file,
+// so it can not be used with WithProxy() or WithSocketFileMode().
+func WithTransport(t Transport) Option {
+	return func(w *wire) {
+		w.transport = t
+	}
+}
+
+// WithListener sets an already announced listener used on the server side instead of the transport.
+// The listener is closed when the wire is closed. It can not be used with WithSocketFileMode().
+func WithListener(l net.Listener) Option {
+	return func(w *wire) {
+		w.listener = l
+	}
+}
+
+// WithDialer sets the dialer used on the client side instead of the transport.
+// The dialer is responsible for the proxy, so it can not be used with WithProxy().
+func WithDialer(d Dialer) Option {
+	return func(w *wire) {
+		w.dialer = d
+	}
+}
+
+// WithSocketFileMode sets the file permissions of the Unix domain socket created on the server side.
+// The socket is reachable by the path only with the permissions.
+// The socket is bound by the default transport, so it can not be used with WithTransport() or WithListener().
+func WithSocketFileMode(mode os.FileMode) Option {
+	return func(w *wire) {
+		w.socketMode = mode
+	}
+}
+
+// WithProxy sets the proxy used on the client side to dial the server.
+// Supported proxy schemes are socks5, socks5h, http and https, credentials are taken from the URL.
+// TLS set with WithTLS() is negotiated end to end through the proxy.
+// The proxy is dialed by the default transport, so it can not be used with WithTransport() or WithDialer().
+func WithProxy(proxyURL *url.URL) Option {
+	return func(w *wire) {
+		w.proxy = fixedProxy(proxyURL)
+	}
+}
+
+// WithProxyFromEnvironment sets the proxy from the HTTPS_PROXY and NO_PROXY environment variables.
+// It can not be used with WithTransport() or WithDialer(), see WithProxy().
+func WithProxyFromEnvironment() Option {
+	return func(w *wire) {
+		w.proxy = proxyFromEnvironment
+	}
+}
+
+// WithEndpoints adds the server addresses dialed on the client side when the main address is unavailable.
+func WithEndpoints(addrs ...string) Option {
+	return func(w *wire) {
+		w.endpoints = append(w.endpoints, addrs...)
+	}
+}
+
+// WithBalancer sets the balancer of the streams registered by several clients in the hub mode.
+// The default is RoundRobinBalancer().
+func WithBalancer(b Balancer) Option {
+	return func(w *wire) {
+		w.balancer = b
+	}
+}
+
+// WithHubPolicy sets the policy of the streams opened by the clients on the other clients in the hub mode.
+// The streams served by the handlers of the hub are not checked. By default all streams are allowed. See ACL.
+func WithHubPolicy(p HubPolicy) Option {
+	return func(w *wire) {
+		w.hubPolicy = p
+	}
+}
+
+// WithEndpointPolicy sets the order in which the endpoints are dialed. The default is EndpointsInOrder.
+func WithEndpointPolicy(p EndpointPolicy) Option {
+	return func(w *wire) {
+		w.endpointPolicy = p
+	}
+}
+
+// WithDialTimeout sets the timeout of each endpoint dial on the client side,
+// including the proxy and the TLS handshake. A blackholed endpoint fails over to the next one after the timeout.
+// Zero disables the timeout. The default is DefaultDialTimeout.
+func WithDialTimeout(dur time.Duration) Option {
+	return func(w *wire) {
+		w.dialTimeout = dur
+	}
+}
+
+// WithSRV adds the endpoints from the DNS SRV records looked up before each connection attempt.
+// See net.LookupSRV().
+func WithSRV(service, proto, name string) Option {
+	return func(w *wire) {
+		w.srv = &srvRecord{
+			service: service,
+			proto:   proto,
+			name:    name,
+		}
+	}
+}
+
 func WithRetryWait(min, max time.Duration) Option {
 	return func(w *wire) {
 		w.retryWaitMax = max
@@ -75,6 +191,7 @@ func WithRetryWait(min, max time.Duration) Option {
 	}
 }
 
+// WithRetryMax sets the maximum number of connection attempts. Use RetryForever to retry without limit.
 func WithRetryMax(n int) Option {
 	return func(w *wire) {
 		w.retryMax = n
@@ -114,12 +231,57 @@ func WithSessionCloseTimeout(dur time.Duration) Option {
 	}
 }
 
+// WithSessionResumption enables the session resumption after reconnect.
+// The client side presents the previous session id and the resume ticket issued at handshake,
+// the server side rebinds the new connection to the existing session within the grace window.
+// The resumed session keeps the same id and the session hooks are not called again.
+func WithSessionResumption(grace time.Duration) Option {
+	return func(w *wire) {
+		w.resumeGrace = grace
+	}
+}
+
 func WithRetryPolicy(rp RetryPolicy) Option {
 	return func(w *wire) {
 		w.retryPolicy = rp
 	}
 }
 
+// WithRetryResetAfter sets the duration after which the established connection is considered healthy.
+// If the session drops after being healthy, the attempts start from the first one without waiting,
+// otherwise the attempts continue with the backoff of the retry policy.
+// The default is zero, every established connection is healthy.
+func WithRetryResetAfter(dur time.Duration) Option {
+	return func(w *wire) {
+		w.retryResetAfter = dur
+	}
+}
+
+// WithRemoteForwarding allows the peer to listen on this side with ForwardRemote(), like ssh -R.
+// The validator allows the requested address, if the validator is nil any address is allowed.
+func WithRemoteForwarding(v ForwardValidator) Option {
+	return func(w *wire) {
+		w.forwardValidator = v
+		w.handlers[ForwardStreamName] = w.serveRemoteForward
+	}
+}
+
+// WithRPCCodec sets the codec of the RPC requests and responses. The default is JSONCodec.
+// The peer decodes the calls with the same codec or with the built-in codec of the same name.
+func WithRPCCodec(codec Codec) Option {
+	return func(w *wire) {
+		w.rpcCodec = codec
+	}
+}
+
+// WithRetryableError sets the classification of the dial errors used on the client side.
+// If the function returns false, the client side stops trying to connect.
+func WithRetryableError(fn RetryableError) Option {
+	return func(w *wire) {
+		w.isRetryable = fn
+	}
+}
+
 func DefaultRetryPolicy(min, max time.Duration, attemptNum int) time.Duration {
 	m := math.Pow(2, float64(attemptNum)) * float64(min)
 	wait := time.Duration(m)
@@ -25,6 +25,7 @@ type OpenSessionRequest struct {
 	Token                []byte   `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
 	Identification       []byte   `protobuf:"bytes,3,opt,name=identification,proto3" json:"identification,omitempty"`
 	LocalStreamNames     []string `protobuf:"bytes,4,rep,name=local_stream_names,json=localStreamNames,proto3" json:"local_stream_names,omitempty"`
+	ResumeTicket         []byte   `protobuf:"bytes,5,opt,name=resume_ticket,json=resumeTicket,proto3" json:"resume_ticket,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -83,10 +84,19 @@ func (m *OpenSessionRequest) GetLocalStreamNames() []string {
 	return nil
 }
 
+func (m *OpenSessionRequest) GetResumeTicket() []byte {
+	if m != nil {
+		return m.ResumeTicket
+	}
+	return nil
+}
+
 type OpenSessionResponse struct {
 	Sid                  []byte   `protobuf:"bytes,1,opt,name=sid,proto3" json:"sid,omitempty"`
 	RemoteStreamNames    []string `protobuf:"bytes,2,rep,name=remote_stream_names,json=remoteStreamNames,proto3" json:"remote_stream_names,omitempty"`
 	Err                  string   `protobuf:"bytes,3,opt,name=err,proto3" json:"err,omitempty"`
+	ResumeTicket         []byte   `protobuf:"bytes,4,opt,name=resume_ticket,json=resumeTicket,proto3" json:"resume_ticket,omitempty"`
+	Resumed              bool     `protobuf:"varint,5,opt,name=resumed,proto3" json:"resumed,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -138,26 +148,247 @@ func (m *OpenSessionResponse) GetErr() string {
 	return ""
 }
 
+func (m *OpenSessionResponse) GetResumeTicket() []byte {
+	if m != nil {
+		return m.ResumeTicket
+	}
+	return nil
+}
+
+func (m *OpenSessionResponse) GetResumed() bool {
+	if m != nil {
+		return m.Resumed
+	}
+	return false
+}
+
+type OpenStreamRequest struct {
+	Token                []byte            `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Metadata             map[string]string `protobuf:"bytes,2,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *OpenStreamRequest) Reset()         { *m = OpenStreamRequest{} }
+func (m *OpenStreamRequest) String() string { return proto.CompactTextString(m) }
+func (*OpenStreamRequest) ProtoMessage()    {}
+func (*OpenStreamRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_387f10401efe34ae, []int{2}
+}
+
+func (m *OpenStreamRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenStreamRequest.Unmarshal(m, b)
+}
+func (m *OpenStreamRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenStreamRequest.Marshal(b, m, deterministic)
+}
+func (m *OpenStreamRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenStreamRequest.Merge(m, src)
+}
+func (m *OpenStreamRequest) XXX_Size() int {
+	return xxx_messageInfo_OpenStreamRequest.Size(m)
+}
+func (m *OpenStreamRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenStreamRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenStreamRequest proto.InternalMessageInfo
+
+func (m *OpenStreamRequest) GetToken() []byte {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *OpenStreamRequest) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type RPCRequest struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Method               string   `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Payload              []byte   `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Timeout              int64    `protobuf:"varint,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Cancel               bool     `protobuf:"varint,5,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RPCRequest) Reset()         { *m = RPCRequest{} }
+func (m *RPCRequest) String() string { return proto.CompactTextString(m) }
+func (*RPCRequest) ProtoMessage()    {}
+func (*RPCRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_387f10401efe34ae, []int{3}
+}
+
+func (m *RPCRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RPCRequest.Unmarshal(m, b)
+}
+func (m *RPCRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RPCRequest.Marshal(b, m, deterministic)
+}
+func (m *RPCRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RPCRequest.Merge(m, src)
+}
+func (m *RPCRequest) XXX_Size() int {
+	return xxx_messageInfo_RPCRequest.Size(m)
+}
+func (m *RPCRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RPCRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RPCRequest proto.InternalMessageInfo
+
+func (m *RPCRequest) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *RPCRequest) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *RPCRequest) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *RPCRequest) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *RPCRequest) GetCancel() bool {
+	if m != nil {
+		return m.Cancel
+	}
+	return false
+}
+
+type RPCResponse struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Payload              []byte   `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	Failed               bool     `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	Code                 uint32   `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RPCResponse) Reset()         { *m = RPCResponse{} }
+func (m *RPCResponse) String() string { return proto.CompactTextString(m) }
+func (*RPCResponse) ProtoMessage()    {}
+func (*RPCResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_387f10401efe34ae, []int{4}
+}
+
+func (m *RPCResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RPCResponse.Unmarshal(m, b)
+}
+func (m *RPCResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RPCResponse.Marshal(b, m, deterministic)
+}
+func (m *RPCResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RPCResponse.Merge(m, src)
+}
+func (m *RPCResponse) XXX_Size() int {
+	return xxx_messageInfo_RPCResponse.Size(m)
+}
+func (m *RPCResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RPCResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RPCResponse proto.InternalMessageInfo
+
+func (m *RPCResponse) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetFailed() bool {
+	if m != nil {
+		return m.Failed
+	}
+	return false
+}
+
+func (m *RPCResponse) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*OpenSessionRequest)(nil), "pb.OpenSessionRequest")
 	proto.RegisterType((*OpenSessionResponse)(nil), "pb.OpenSessionResponse")
+	proto.RegisterType((*OpenStreamRequest)(nil), "pb.OpenStreamRequest")
+	proto.RegisterMapType((map[string]string)(nil), "pb.OpenStreamRequest.MetadataEntry")
+	proto.RegisterType((*RPCRequest)(nil), "pb.RPCRequest")
+	proto.RegisterType((*RPCResponse)(nil), "pb.RPCResponse")
 }
 
 func init() { proto.RegisterFile("pb/session.proto", fileDescriptor_387f10401efe34ae) }
 
 var fileDescriptor_387f10401efe34ae = []byte{
-	// 201 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x90, 0xc1, 0x6a, 0x84, 0x30,
-	0x10, 0x86, 0x51, 0xdb, 0x82, 0x43, 0x29, 0x36, 0xf6, 0x90, 0xa3, 0x78, 0x28, 0x1e, 0x8a, 0x3d,
-	0xf4, 0x3d, 0x5a, 0x88, 0x0f, 0x20, 0x51, 0xa7, 0x10, 0xaa, 0x99, 0x34, 0x93, 0x7d, 0x91, 0x7d,
-	0xe2, 0x25, 0xf1, 0xb2, 0x2e, 0x7b, 0xfb, 0xe7, 0xff, 0xe0, 0xe3, 0x67, 0xa0, 0x72, 0xd3, 0x27,
-	0x23, 0xb3, 0x21, 0xdb, 0x3b, 0x4f, 0x81, 0x44, 0xee, 0xa6, 0xf6, 0x9c, 0x81, 0xf8, 0x71, 0x68,
-	0x87, 0x9d, 0x28, 0xfc, 0x3f, 0x21, 0x07, 0x51, 0x41, 0xc1, 0x66, 0x91, 0x59, 0x93, 0x75, 0xcf,
-	0x2a, 0x46, 0xf1, 0x06, 0x8f, 0x81, 0xfe, 0xd0, 0xca, 0x3c, 0x75, 0xfb, 0x21, 0xde, 0xe1, 0xc5,
-	0x2c, 0x68, 0x83, 0xf9, 0x35, 0xb3, 0x0e, 0x86, 0xac, 0x2c, 0x12, 0xbe, 0x69, 0xc5, 0x07, 0x88,
-	0x95, 0x66, 0xbd, 0x8e, 0x1c, 0x3c, 0xea, 0x6d, 0xb4, 0x7a, 0x43, 0x96, 0x0f, 0x4d, 0xd1, 0x95,
-	0xaa, 0x4a, 0x64, 0x48, 0xe0, 0x3b, 0xf6, 0xad, 0x81, 0xfa, 0xb0, 0x89, 0x1d, 0x59, 0xc6, 0x3b,
-	0xa3, 0x7a, 0xa8, 0x3d, 0x6e, 0x14, 0xf0, 0xe8, 0xcd, 0x93, 0xf7, 0x75, 0x47, 0x57, 0xe2, 0x68,
-	0x40, 0xef, 0xd3, 0xc6, 0x52, 0xc5, 0x38, 0x3d, 0xa5, 0x57, 0x7c, 0x5d, 0x02, 0x00, 0x00, 0xff,
-	0xff, 0x73, 0xe0, 0x37, 0x75, 0x1e, 0x01, 0x00, 0x00,
+	// 428 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x4d, 0x6f, 0xd4, 0x30,
+	0x10, 0x55, 0x92, 0xed, 0xb2, 0x99, 0x76, 0xab, 0xad, 0x8b, 0xaa, 0x88, 0x53, 0x95, 0x4a, 0xa8,
+	0x07, 0x14, 0x24, 0xb8, 0x20, 0x38, 0x70, 0x40, 0x1c, 0xf9, 0x90, 0xcb, 0x7d, 0xe5, 0x8d, 0xa7,
+	0x60, 0x6d, 0x6c, 0x87, 0xd8, 0x8b, 0xb4, 0x07, 0x24, 0xfe, 0x0d, 0xfc, 0x03, 0xfe, 0x1e, 0xf2,
+	0xd8, 0xa9, 0xf6, 0x83, 0xdb, 0xbc, 0x79, 0xf1, 0xbc, 0x37, 0x6f, 0x14, 0x58, 0xf4, 0xab, 0xe7,
+	0x0e, 0x9d, 0x53, 0xd6, 0x34, 0xfd, 0x60, 0xbd, 0x65, 0x79, 0xbf, 0xaa, 0xff, 0x66, 0xc0, 0x3e,
+	0xf5, 0x68, 0xee, 0x22, 0xc3, 0xf1, 0xfb, 0x06, 0x9d, 0x67, 0x0b, 0x28, 0x9c, 0x92, 0x55, 0x76,
+	0x9d, 0xdd, 0x9e, 0xf1, 0x50, 0xb2, 0xc7, 0x70, 0xe2, 0xed, 0x1a, 0x4d, 0x95, 0x53, 0x2f, 0x02,
+	0xf6, 0x14, 0xce, 0x95, 0x44, 0xe3, 0xd5, 0xbd, 0x6a, 0x85, 0x57, 0xd6, 0x54, 0x05, 0xd1, 0x07,
+	0x5d, 0xf6, 0x0c, 0x58, 0x67, 0x5b, 0xd1, 0x2d, 0x9d, 0x1f, 0x50, 0xe8, 0xa5, 0x11, 0x1a, 0x5d,
+	0x35, 0xb9, 0x2e, 0x6e, 0x4b, 0xbe, 0x20, 0xe6, 0x8e, 0x88, 0x8f, 0xa1, 0xcf, 0x6e, 0x60, 0x3e,
+	0xa0, 0xdb, 0x68, 0x5c, 0x7a, 0xd5, 0xae, 0xd1, 0x57, 0x27, 0x34, 0xf4, 0x2c, 0x36, 0xbf, 0x50,
+	0xaf, 0xfe, 0x93, 0xc1, 0xe5, 0x9e, 0x73, 0xd7, 0x5b, 0xe3, 0xf0, 0x3f, 0xd6, 0x1b, 0xb8, 0x1c,
+	0x50, 0x5b, 0x8f, 0xfb, 0xea, 0x39, 0xa9, 0x5f, 0x44, 0x6a, 0x57, 0x7e, 0x01, 0x05, 0x0e, 0x03,
+	0x6d, 0x52, 0xf2, 0x50, 0x1e, 0x1b, 0x9a, 0x1c, 0x1b, 0x62, 0x15, 0x3c, 0x8a, 0x58, 0x92, 0xdf,
+	0x19, 0x1f, 0x61, 0xfd, 0x3b, 0x83, 0x0b, 0xb2, 0x4a, 0x22, 0x63, 0xc6, 0x0f, 0x89, 0x66, 0xbb,
+	0x89, 0xbe, 0x85, 0x99, 0x46, 0x2f, 0xa4, 0xf0, 0x82, 0x1c, 0x9e, 0xbe, 0xb8, 0x69, 0xfa, 0x55,
+	0x73, 0xf4, 0xbc, 0xf9, 0x90, 0xbe, 0x7a, 0x6f, 0xfc, 0xb0, 0xe5, 0x0f, 0x8f, 0x9e, 0xbc, 0x81,
+	0xf9, 0x1e, 0x15, 0xd6, 0x59, 0xe3, 0x96, 0x54, 0x4a, 0x1e, 0xca, 0xa0, 0xfc, 0x43, 0x74, 0x1b,
+	0xa4, 0x5b, 0x96, 0x3c, 0x82, 0xd7, 0xf9, 0xab, 0xac, 0xfe, 0x95, 0x01, 0xf0, 0xcf, 0xef, 0x46,
+	0x8b, 0xe7, 0x90, 0xa7, 0x28, 0x27, 0x3c, 0x57, 0x92, 0x5d, 0xc1, 0x54, 0xa3, 0xff, 0x66, 0x65,
+	0x7a, 0x99, 0x50, 0x58, 0xbd, 0x17, 0xdb, 0xce, 0x0a, 0x99, 0xee, 0x3f, 0xc2, 0xc0, 0x78, 0xa5,
+	0xd1, 0x6e, 0x62, 0x66, 0x05, 0x1f, 0x61, 0x98, 0xd5, 0x0a, 0xd3, 0x62, 0x97, 0xd2, 0x4a, 0xa8,
+	0xfe, 0x09, 0xa7, 0xe4, 0x20, 0x9d, 0xf3, 0xd0, 0xc2, 0x8e, 0x54, 0xbe, 0x2f, 0x75, 0x05, 0xd3,
+	0x7b, 0xa1, 0x3a, 0x8c, 0x1e, 0x66, 0x3c, 0x21, 0xc6, 0x60, 0xd2, 0x5a, 0x89, 0xa4, 0x3f, 0xe7,
+	0x54, 0x87, 0x29, 0x1a, 0x9d, 0x13, 0x5f, 0x91, 0xd4, 0x4b, 0x3e, 0xc2, 0xd5, 0x94, 0xfe, 0x8d,
+	0x97, 0xff, 0x06, 0x00, 0x10, 0xd9, 0x6a, 0x4b, 0x2f, 0x03, 0x00, 0x00,
 }
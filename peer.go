@@ -0,0 +1,47 @@
+package wirenet
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+// PeerHeader is the metadata key of the identification of the client addressed through the hub.
+// The identification is encoded with base64, see WithPeer().
+const PeerHeader = "wirenet.peer"
+
+// WithPeer addresses the stream to the client with the identification in the hub mode,
+// so each client can register the same stream name. The hub removes the header before the stream is relayed.
+func WithPeer(id Identification) StreamOption {
+	return WithStreamMetadata(Metadata{PeerHeader: base64.StdEncoding.EncodeToString(id)})
+}
+
+// OpenStreamTo opens the named stream of the client with the identification through the hub.
+// It is the same as sess.OpenStreamContext(context.Background(), name, WithPeer(id)).
+func OpenStreamTo(sess Session, id Identification, name string) (Stream, error) {
+	return sess.OpenStreamContext(context.Background(), name, WithPeer(id))
+}
+
+// peerStream is the key of the stream of the client in the hub index.
+type peerStream struct {
+	id     string
+	stream string
+}
+
+// streamPeer returns the identification of the addressed client and the metadata without the peer header.
+func streamPeer(md Metadata) (Identification, Metadata, error) {
+	value, ok := md[PeerHeader]
+	if !ok {
+		return nil, md, nil
+	}
+	id, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, md, err
+	}
+	rest := make(Metadata, len(md)-1)
+	for k, v := range md {
+		if k != PeerHeader {
+			rest[k] = v
+		}
+	}
+	return id, rest, nil
+}
@@ -0,0 +1,100 @@
+package wirenet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPeer(t *testing.T) {
+	opts := &streamOptions{metadata: Metadata{"request-id": "1"}}
+	WithPeer(Identification("team/macbook"))(opts)
+	peer, rest, err := streamPeer(opts.metadata)
+	assert.Nil(t, err)
+	assert.Equal(t, Identification("team/macbook"), peer)
+	assert.Equal(t, Metadata{"request-id": "1"}, rest)
+
+	peer, rest, err = streamPeer(Metadata{"request-id": "1"})
+	assert.Nil(t, err)
+	assert.Nil(t, peer)
+	assert.Equal(t, Metadata{"request-id": "1"}, rest)
+
+	_, _, err = streamPeer(Metadata{PeerHeader: "%"})
+	assert.NotNil(t, err)
+}
+
+func TestHub_OpenStreamTo(t *testing.T) {
+	addr := genAddr(t)
+	initHub := make(chan struct{})
+	hub, err := Hub(addr, WithConnectHook(func(closer io.Closer) {
+		close(initHub)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, hub.Connect())
+	}()
+	<-initHub
+
+	var wg sync.WaitGroup
+	join := func(id string, streams ...string) (Wire, Session) {
+		var sess Session
+		wg.Add(1)
+		client, err := Join(addr,
+			WithIdentification(Identification(id), nil),
+			WithSessionOpenHook(func(s Session) {
+				sess = s
+				wg.Done()
+			}))
+		assert.Nil(t, err)
+		for _, name := range streams {
+			client.Stream(name, func(ctx context.Context, s Stream) {
+				buf := bytes.NewBuffer(nil)
+				_, _ = s.WriteTo(buf)
+				buf.WriteString(" from " + id)
+				if _, ok := s.Metadata()[PeerHeader]; ok {
+					buf.WriteString(" with the peer header")
+				}
+				_, _ = s.ReadFrom(buf)
+			})
+		}
+		go func() {
+			assert.Nil(t, client.Connect())
+		}()
+		wg.Wait()
+		return client, sess
+	}
+	macbook, sess := join("team/macbook", "shell")
+	pc, _ := join("pc", "shell")
+	// the stream name of the other client can not be registered to receive its streams
+	evil, _ := join("evil", "team/macbook/shell", "pc/shell")
+
+	call := func(s Stream, err error) string {
+		assert.Nil(t, err)
+		_, err = s.ReadFrom(strings.NewReader("ls"))
+		assert.Nil(t, err)
+		resp, err := ioutil.ReadAll(s.Reader())
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+		return string(resp)
+	}
+	assert.Equal(t, "ls from pc", call(OpenStreamTo(sess, Identification("pc"), "shell")))
+	assert.Equal(t, "ls from team/macbook", call(OpenStreamTo(sess, Identification("team/macbook"), "shell")))
+	assert.Equal(t, "ls from pc", call(sess.OpenStreamContext(context.Background(), "shell", WithPeer(Identification("pc")))))
+	assert.Equal(t, "ls from evil", call(sess.OpenStream("pc/shell")))
+
+	_, err = OpenStreamTo(sess, Identification("tablet"), "shell")
+	assert.NotNil(t, err)
+	_, err = OpenStreamTo(sess, Identification("pc"), "pc/shell")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, evil.Close())
+	assert.Nil(t, pc.Close())
+	assert.Nil(t, macbook.Close())
+	assert.Nil(t, hub.Close())
+}
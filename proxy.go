@@ -0,0 +1,237 @@
+package wirenet
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	socks5Version      = 0x05
+	socks5AuthNone     = 0x00
+	socks5AuthPassword = 0x02
+	socks5CmdConnect   = 0x01
+	socks5AtypIPv4     = 0x01
+	socks5AtypDomain   = 0x03
+	socks5AtypIPv6     = 0x04
+	socks5Succeeded    = 0x00
+)
+
+// proxyFunc returns the proxy URL for the given addr. If the URL is nil, the addr is dialed directly.
+type proxyFunc func(addr string) (*url.URL, error)
+
+func fixedProxy(proxyURL *url.URL) proxyFunc {
+	return func(string) (*url.URL, error) {
+		return proxyURL, nil
+	}
+}
+
+// proxyFromEnvironment returns the proxy URL from the HTTPS_PROXY and NO_PROXY environment variables.
+func proxyFromEnvironment(addr string) (*url.URL, error) {
+	return http.ProxyFromEnvironment(&http.Request{
+		URL: &url.URL{Scheme: "https", Host: addr},
+	})
+}
+
+func dialProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	proxyConn := conn
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxyURL.User, addr)
+	case "http":
+		proxyConn, err = httpConnect(conn, proxyURL.User, addr)
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		proxyConn, err = httpConnect(tlsConn, proxyURL.User, addr)
+	default:
+		err = fmt.Errorf("%w %q", ErrUnknownProxyScheme, proxyURL.Scheme)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return proxyConn, nil
+}
+
+func proxyAddr(proxyURL *url.URL) string {
+	if len(proxyURL.Port()) > 0 {
+		return proxyURL.Host
+	}
+	port := "1080"
+	switch proxyURL.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+func socks5Connect(conn net.Conn, user *url.Userinfo, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 0xffff {
+		return fmt.Errorf("wirenet: invalid port %q", portStr)
+	}
+
+	methods := []byte{socks5AuthNone}
+	if user != nil {
+		methods = append(methods, socks5AuthPassword)
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[0] != socks5Version {
+		return ErrProxyProtocol
+	}
+	switch resp[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if user == nil {
+			return ErrProxyAuthFailed
+		}
+		password, _ := user.Password()
+		username := user.Username()
+		if len(username) > 255 || len(password) > 255 {
+			return ErrProxyAuthFailed
+		}
+		req := []byte{0x01, byte(len(username))}
+		req = append(req, username...)
+		req = append(req, byte(len(password)))
+		req = append(req, password...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return err
+		}
+		if resp[1] != socks5Succeeded {
+			return ErrProxyAuthFailed
+		}
+	default:
+		return ErrProxyAuthFailed
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AtypIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AtypIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("wirenet: host name too long %q", host)
+		}
+		req = append(req, socks5AtypDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = append(req, 0, 0)
+	binary.BigEndian.PutUint16(req[len(req)-2:], uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return err
+	}
+	if hdr[0] != socks5Version {
+		return ErrProxyProtocol
+	}
+	if hdr[1] != socks5Succeeded {
+		return fmt.Errorf("wirenet: socks5 connect to %s failed with code %d", addr, hdr[1])
+	}
+	var skip int
+	switch hdr[3] {
+	case socks5AtypIPv4:
+		skip = net.IPv4len
+	case socks5AtypIPv6:
+		skip = net.IPv6len
+	case socks5AtypDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0])
+	default:
+		return ErrProxyProtocol
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+func httpConnect(conn net.Conn, user *url.Userinfo, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusProxyAuthRequired:
+		return nil, ErrProxyAuthFailed
+	default:
+		return nil, errors.New("wirenet: proxy connect to " + addr + " failed: " + resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
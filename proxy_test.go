@@ -0,0 +1,213 @@
+package wirenet
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveProxy(t *testing.T, handshake func(conn net.Conn) (string, bool)) (addr string, closer io.Closer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				dstAddr, ok := handshake(conn)
+				if !ok {
+					return
+				}
+				dst, err := net.Dial("tcp", dstAddr)
+				if err != nil {
+					return
+				}
+				defer dst.Close()
+				go func() {
+					_ = pipe(dst, conn)
+					conn.Close()
+				}()
+				_ = pipe(conn, dst)
+			}()
+		}
+	}()
+	return listener.Addr().String(), listener
+}
+
+func socks5Handshake(user, password string) func(conn net.Conn) (string, bool) {
+	return func(conn net.Conn) (string, bool) {
+		hdr := make([]byte, 2)
+		if _, err := io.ReadFull(conn, hdr); err != nil {
+			return "", false
+		}
+		methods := make([]byte, hdr[1])
+		if _, err := io.ReadFull(conn, methods); err != nil {
+			return "", false
+		}
+		if len(user) > 0 {
+			conn.Write([]byte{socks5Version, socks5AuthPassword})
+			r := bufio.NewReader(conn)
+			r.ReadByte()
+			ul, _ := r.ReadByte()
+			u := make([]byte, ul)
+			io.ReadFull(r, u)
+			pl, _ := r.ReadByte()
+			p := make([]byte, pl)
+			io.ReadFull(r, p)
+			if string(u) != user || string(p) != password {
+				conn.Write([]byte{0x01, 0x01})
+				return "", false
+			}
+			conn.Write([]byte{0x01, socks5Succeeded})
+		} else {
+			conn.Write([]byte{socks5Version, socks5AuthNone})
+		}
+		req := make([]byte, 4)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return "", false
+		}
+		var host string
+		switch req[3] {
+		case socks5AtypIPv4:
+			ip := make([]byte, net.IPv4len)
+			io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case socks5AtypDomain:
+			l := make([]byte, 1)
+			io.ReadFull(conn, l)
+			h := make([]byte, l[0])
+			io.ReadFull(conn, h)
+			host = string(h)
+		}
+		port := make([]byte, 2)
+		io.ReadFull(conn, port)
+		conn.Write([]byte{socks5Version, socks5Succeeded, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+	}
+}
+
+func httpConnectHandshake(conn net.Conn) (string, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return "", false
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return "", false
+	}
+	return req.Host, true
+}
+
+func testProxyConnect(t *testing.T, proxyURL *url.URL) {
+	addr := "127.0.0.1" + genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// server side
+	serverTLSConf, err := LoadCertificates("server", "./certs")
+	assert.Nil(t, err)
+	server, err := Mount(addr,
+		WithTLS(serverTLSConf),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	clientTLSConf, err := LoadCertificates("client", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf.InsecureSkipVerify = true
+	client, err := Join(addr,
+		WithTLS(clientTLSConf),
+		WithProxy(proxyURL),
+		WithSessionOpenHook(func(s Session) {
+			close(initCli)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectSOCKS5Proxy(t *testing.T) {
+	srvAddr, closer := serveProxy(t, socks5Handshake("user", "secret"))
+	defer closer.Close()
+	testProxyConnect(t, &url.URL{
+		Scheme: "socks5",
+		Host:   srvAddr,
+		User:   url.UserPassword("user", "secret"),
+	})
+}
+
+func TestWire_ConnectHTTPProxy(t *testing.T) {
+	srvAddr, closer := serveProxy(t, httpConnectHandshake)
+	defer closer.Close()
+	testProxyConnect(t, &url.URL{
+		Scheme: "http",
+		Host:   srvAddr,
+	})
+}
+
+func TestWire_ProxyConflictingOptions(t *testing.T) {
+	proxyURL := &url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"}
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		return nil, io.EOF
+	}
+
+	w, err := Join(":8989", WithProxy(proxyURL), WithDialer(dialer))
+	assert.Nil(t, w)
+	assert.True(t, errors.Is(err, ErrConflictingOptions))
+
+	w, err = Join(":8989", WithProxyFromEnvironment(), WithTransport(newPipeListener()))
+	assert.Nil(t, w)
+	assert.True(t, errors.Is(err, ErrConflictingOptions))
+
+	w, err = Join(":8989", WithProxy(proxyURL))
+	assert.Nil(t, err)
+	assert.NotNil(t, w)
+}
+
+func TestDialProxy(t *testing.T) {
+	srvAddr, closer := serveProxy(t, socks5Handshake("user", "secret"))
+	defer closer.Close()
+
+	var dialer net.Dialer
+	_, err := dialProxy(context.Background(), &dialer, &url.URL{
+		Scheme: "socks5",
+		Host:   srvAddr,
+		User:   url.UserPassword("user", "wrong"),
+	}, "127.0.0.1:80")
+	assert.Equal(t, ErrProxyAuthFailed, err)
+
+	_, err = dialProxy(context.Background(), &dialer, &url.URL{
+		Scheme: "ftp",
+		Host:   srvAddr,
+	}, "127.0.0.1:80")
+	assert.True(t, errors.Is(err, ErrUnknownProxyScheme))
+}
+
+func TestProxyAddr(t *testing.T) {
+	assert.Equal(t, "proxy:1080", proxyAddr(&url.URL{Scheme: "socks5", Host: "proxy"}))
+	assert.Equal(t, "proxy:80", proxyAddr(&url.URL{Scheme: "http", Host: "proxy"}))
+	assert.Equal(t, "proxy:443", proxyAddr(&url.URL{Scheme: "https", Host: "proxy"}))
+	assert.Equal(t, "proxy:3128", proxyAddr(&url.URL{Scheme: "http", Host: "proxy:3128"}))
+}
@@ -0,0 +1,160 @@
+package wirenet
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/yamux"
+	"github.com/mediabuyerbot/go-wirenet/pb"
+)
+
+const resumeTicketSize = 32
+
+// handshake is the result of the session opening on the client side or the session confirmation on the server side.
+type handshake struct {
+	sid            uuid.UUID
+	identification Identification
+	streamNames    []string
+	ticket         []byte
+	resumed        *session
+}
+
+func newResumeTicket() ([]byte, error) {
+	ticket := make([]byte, resumeTicketSize)
+	if _, err := rand.Read(ticket); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func (s *session) isDetached() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.detached
+}
+
+func (s *session) resumeTicket() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ticket
+}
+
+// detach keeps the session after the connection drop until it is resumed on a new connection.
+// On the server side the session is closed after the grace window.
+// Returns a false flag if the session must be closed.
+func (s *session) detach(conn *yamux.Session) bool {
+	resumable := s.w.resumeGrace > 0 && !s.w.isClosed()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != conn {
+		// already resumed on a new connection
+		return !s.closed
+	}
+	if s.closed || !resumable || len(s.ticket) == 0 {
+		return false
+	}
+	s.detached = true
+	if !s.w.role.IsClientSide() {
+		s.expiry = time.AfterFunc(s.w.resumeGrace, func() {
+			_ = s.Close()
+		})
+	}
+	return true
+}
+
+// rebind binds the session to a new connection if the resume ticket is valid.
+// The previous connection of the session is closed.
+func (s *session) rebind(conn *yamux.Session, ticket, newTicket []byte) bool {
+	s.mu.Lock()
+	if s.closed || subtle.ConstantTimeCompare(s.ticket, ticket) != 1 {
+		s.mu.Unlock()
+		return false
+	}
+	if s.expiry != nil && !s.expiry.Stop() {
+		// the grace window is over, the session is closing
+		s.mu.Unlock()
+		return false
+	}
+	prevConn := s.conn
+	s.conn = conn
+	s.ticket = newTicket
+	s.detached = false
+	s.expiry = nil
+	s.mu.Unlock()
+
+	if prevConn != conn {
+		_ = prevConn.Close()
+	}
+	return true
+}
+
+// detachSession drops the client side connection, so the wire reconnects and resumes the session.
+func (w *wire) detachSession(s *session) {
+	if w.isClosed() {
+		return
+	}
+	w.mu.Lock()
+	w.detached = s
+	w.mu.Unlock()
+
+	req := &shutdownRequest{
+		detach: true,
+		errCh:  make(chan *ShutdownError),
+	}
+	if w.sendShutdown(req) {
+		<-req.errCh
+	}
+}
+
+// takeDetached returns the detached client side session and forgets it.
+func (w *wire) takeDetached() *session {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.detached
+	w.detached = nil
+	return s
+}
+
+// resumeSession rebinds the detached server side session to the connection if the resume ticket is valid.
+// A new resume ticket is issued for both the resumed and the new session.
+func (w *wire) resumeSession(conn *yamux.Session, req *pb.OpenSessionRequest, resp *pb.OpenSessionResponse) (*session, error) {
+	if w.resumeGrace <= 0 {
+		return nil, nil
+	}
+	ticket, err := newResumeTicket()
+	if err != nil {
+		return nil, err
+	}
+	resp.ResumeTicket = ticket
+	if len(req.ResumeTicket) == 0 {
+		return nil, nil
+	}
+
+	// the new session never takes the id of the existing session
+	newSid, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sid, err := uuid.FromBytes(req.Sid)
+	if err != nil {
+		resp.Sid = newSid
+		return nil, nil
+	}
+	w.mu.RLock()
+	found, ok := w.sessions[sid]
+	w.mu.RUnlock()
+	if !ok {
+		resp.Sid = newSid
+		return nil, nil
+	}
+	sess := found.(*session)
+	if !sess.rebind(conn, req.ResumeTicket, ticket) {
+		resp.Sid = newSid
+		return nil, nil
+	}
+	resp.Resumed = true
+	return sess, nil
+}
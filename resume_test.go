@@ -0,0 +1,197 @@
+package wirenet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// dropDialer dials tcp and keeps the last connection to drop it.
+type dropDialer struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (d *dropDialer) dial(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+	return conn, nil
+}
+
+func (d *dropDialer) drop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_ = d.conn.Close()
+}
+
+func sessionIDs(sessions Sessions) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for id := range sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestWire_SessionResumption(t *testing.T) {
+	addr := "127.0.0.1" + genAddr(t)
+	initSrv := make(chan struct{})
+	connected := make(chan struct{}, 10)
+	var srvOpened, srvClosed, cliOpened, cliClosed int32
+
+	// server side
+	server, err := Mount(addr,
+		WithSessionResumption(time.Minute),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}),
+		WithSessionOpenHook(func(s Session) {
+			atomic.AddInt32(&srvOpened, 1)
+		}),
+		WithSessionCloseHook(func(s Session) {
+			atomic.AddInt32(&srvClosed, 1)
+		}),
+	)
+	assert.Nil(t, err)
+	server.Stream("echo", func(ctx context.Context, stream Stream) {
+		msg, err := ioutil.ReadAll(stream.Reader())
+		assert.Nil(t, err)
+		_, err = stream.ReadFrom(bytes.NewReader(msg))
+		assert.Nil(t, err)
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	dialer := new(dropDialer)
+	client, err := Join(addr,
+		WithSessionResumption(time.Minute),
+		WithDialer(dialer.dial),
+		WithRetryWait(10*time.Millisecond, 10*time.Millisecond),
+		WithSessionOpenHook(func(s Session) {
+			atomic.AddInt32(&cliOpened, 1)
+		}),
+		WithSessionCloseHook(func(s Session) {
+			atomic.AddInt32(&cliClosed, 1)
+		}),
+		WithStateHook(func(state State, info StateInfo) {
+			if state == StateConnected {
+				connected <- struct{}{}
+			}
+		}),
+	)
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-connected
+	time.Sleep(100 * time.Millisecond)
+
+	ids := sessionIDs(client.Sessions())
+	assert.Len(t, ids, 1)
+	assert.Equal(t, ids, sessionIDs(server.Sessions()))
+	sess := client.Sessions()[ids[0]]
+
+	for i := 0; i < 3; i++ {
+		dialer.drop()
+		<-connected
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, ids, sessionIDs(client.Sessions()))
+		assert.Equal(t, ids, sessionIDs(server.Sessions()))
+		assert.False(t, sess.IsClosed())
+
+		stream, err := sess.OpenStream("echo")
+		assert.Nil(t, err)
+		w := stream.Writer()
+		_, err = w.Write([]byte("ping"))
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(stream.Reader(), buf)
+		assert.Nil(t, err)
+		assert.Equal(t, "ping", string(buf))
+		assert.Nil(t, stream.Close())
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&srvOpened))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&srvClosed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cliOpened))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&cliClosed))
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_SessionResumptionExpired(t *testing.T) {
+	addr := "127.0.0.1" + genAddr(t)
+	initSrv := make(chan struct{})
+	connected := make(chan struct{}, 10)
+	srvClosed := make(chan Session, 10)
+
+	// server side
+	server, err := Mount(addr,
+		WithSessionResumption(100*time.Millisecond),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}),
+		WithSessionCloseHook(func(s Session) {
+			srvClosed <- s
+		}),
+	)
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side without resumption
+	dialer := new(dropDialer)
+	client, err := Join(addr,
+		WithDialer(dialer.dial),
+		WithRetryWait(10*time.Millisecond, 10*time.Millisecond),
+		WithStateHook(func(state State, info StateInfo) {
+			if state == StateConnected {
+				connected <- struct{}{}
+			}
+		}),
+	)
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-connected
+	time.Sleep(100 * time.Millisecond)
+	prevIDs := sessionIDs(client.Sessions())
+	assert.Len(t, prevIDs, 1)
+
+	dialer.drop()
+	<-connected
+
+	// the detached session is closed after the grace window
+	sess := <-srvClosed
+	assert.Equal(t, prevIDs[0], sess.ID())
+	time.Sleep(100 * time.Millisecond)
+	ids := sessionIDs(server.Sessions())
+	assert.Len(t, ids, 1)
+	assert.NotEqual(t, prevIDs, ids)
+	assert.Equal(t, ids, sessionIDs(client.Sessions()))
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
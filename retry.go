@@ -0,0 +1,130 @@
+package wirenet
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryForever is used with WithRetryMax() to retry connecting without limit.
+const RetryForever = -1
+
+var jitter = struct {
+	sync.Mutex
+	rnd *rand.Rand
+}{
+	rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+}
+
+func randDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	jitter.Lock()
+	defer jitter.Unlock()
+	return min + time.Duration(jitter.rnd.Int63n(int64(max-min)+1))
+}
+
+// FullJitterRetryPolicy returns a random wait between zero and the exponential backoff of DefaultRetryPolicy.
+// Spreads the reconnection of many clients to a restarted server.
+func FullJitterRetryPolicy(min, max time.Duration, attemptNum int) time.Duration {
+	return randDuration(0, DefaultRetryPolicy(min, max, attemptNum))
+}
+
+// DecorrelatedJitterRetryPolicy constructs a new retry policy that returns a random wait
+// between min and three times the previous wait, capped at max.
+// The policy keeps the previous wait, so each wire must use its own instance.
+func DecorrelatedJitterRetryPolicy() RetryPolicy {
+	var (
+		mu   sync.Mutex
+		prev time.Duration
+	)
+	return func(min, max time.Duration, attemptNum int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		if attemptNum == 0 || prev < min {
+			prev = min
+		}
+		upper := prev * 3
+		if upper > max || upper < prev {
+			upper = max
+		}
+		prev = randDuration(min, upper)
+		return prev
+	}
+}
+
+// RetryableError reports whether the client side should try to connect again after the dial error.
+// The default is DefaultRetryableError, but you can write your own classification.
+type RetryableError func(err error) bool
+
+var retryableErrnos = []syscall.Errno{
+	syscall.ECONNREFUSED,
+	syscall.ECONNRESET,
+	syscall.ECONNABORTED,
+	syscall.ENETUNREACH,
+	syscall.EHOSTUNREACH,
+	syscall.ETIMEDOUT,
+	syscall.EPIPE,
+}
+
+// DefaultRetryableError classifies the dial error.
+// Network errors, timeouts, DNS failures and connections dropped during the handshake are retryable.
+// Certificate verification errors, proxy authentication errors and context cancellation are not retryable.
+// The network timeouts match context.DeadlineExceeded, but only the context error itself is not retryable.
+func DefaultRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && netErr != context.DeadlineExceeded {
+		return true
+	}
+
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrAddrEmpty) ||
+		errors.Is(err, ErrProxyAuthFailed) ||
+		errors.Is(err, ErrUnknownProxyScheme) {
+		return false
+	}
+
+	var (
+		unknownAuthorityErr x509.UnknownAuthorityError
+		hostnameErr         x509.HostnameError
+		certInvalidErr      x509.CertificateInvalidError
+		systemRootsErr      x509.SystemRootsError
+		recordHeaderErr     tls.RecordHeaderError
+	)
+	if errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalidErr) ||
+		errors.As(err, &systemRootsErr) ||
+		errors.As(err, &recordHeaderErr) {
+		return false
+	}
+
+	for _, errno := range retryableErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	var (
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+	)
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,170 @@
+package wirenet
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestDefaultRetryableError(t *testing.T) {
+	refusedErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	// the dial timeout matches context.DeadlineExceeded
+	_, dialTimeoutErr := (&net.Dialer{Timeout: time.Nanosecond}).Dial("tcp", "127.0.0.1:1")
+	assert.True(t, errors.Is(dialTimeoutErr, context.DeadlineExceeded))
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: refusedErr, want: true},
+		{err: fmt.Errorf("wrapped: %w", refusedErr), want: true},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)}, want: true},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, want: true},
+		{err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}, want: true},
+		{err: timeoutErr{}, want: true},
+		{err: dialTimeoutErr, want: true},
+		{err: fmt.Errorf("dial: %w", dialTimeoutErr), want: true},
+		{err: io.EOF, want: true},
+		{err: io.ErrUnexpectedEOF, want: true},
+		{err: x509.UnknownAuthorityError{}, want: false},
+		{err: x509.HostnameError{Host: "example.com", Certificate: new(x509.Certificate)}, want: false},
+		{err: x509.CertificateInvalidError{Reason: x509.Expired, Cert: new(x509.Certificate)}, want: false},
+		{err: ErrProxyAuthFailed, want: false},
+		{err: fmt.Errorf("%w %q", ErrUnknownProxyScheme, "ftp"), want: false},
+		{err: context.Canceled, want: false},
+		{err: context.DeadlineExceeded, want: false},
+		{err: fmt.Errorf("dial: %w", context.DeadlineExceeded), want: false},
+		{err: errors.New("some error"), want: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, DefaultRetryableError(tc.err), fmt.Sprint(tc.err))
+	}
+}
+
+func TestWire_RetryableError(t *testing.T) {
+	var retryCounter int
+	var classified error
+	client, err := Join(genAddr(t),
+		WithRetryPolicy(func(min, max time.Duration, attemptNum int) time.Duration {
+			retryCounter++
+			return min
+		}),
+		WithRetryMax(3),
+		WithRetryableError(func(err error) bool {
+			classified = err
+			return false
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, classified, client.Connect())
+	assert.Equal(t, 0, retryCounter)
+	assert.True(t, DefaultRetryableError(classified))
+}
+
+func TestFullJitterRetryPolicy(t *testing.T) {
+	min, max := time.Second, time.Minute
+	for attemptNum := 0; attemptNum < 10; attemptNum++ {
+		upper := DefaultRetryPolicy(min, max, attemptNum)
+		for i := 0; i < 100; i++ {
+			wait := FullJitterRetryPolicy(min, max, attemptNum)
+			assert.True(t, wait >= 0 && wait <= upper, wait.String())
+		}
+	}
+}
+
+func TestDecorrelatedJitterRetryPolicy(t *testing.T) {
+	min, max := time.Second, time.Minute
+	policy := DecorrelatedJitterRetryPolicy()
+	prev := min
+	for attemptNum := 0; attemptNum < 100; attemptNum++ {
+		wait := policy(min, max, attemptNum)
+		assert.True(t, wait >= min && wait <= max, wait.String())
+		assert.True(t, wait <= prev*3, wait.String())
+		prev = wait
+	}
+
+	// reset on the first attempt
+	wait := policy(min, max, 0)
+	assert.True(t, wait >= min && wait <= 3*min, wait.String())
+}
+
+func TestWire_RetryForever(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := Join(genAddr(t),
+		WithRetryMax(RetryForever),
+		WithRetryWait(time.Millisecond, time.Millisecond),
+		WithStateHook(func(state State, info StateInfo) {
+			if state == StateReconnecting && info.Attempt > 5 {
+				cancel()
+			}
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, context.Canceled, client.ConnectContext(ctx))
+	_, info := client.State()
+	assert.True(t, info.Attempt > 5)
+}
+
+func TestWire_RetryResetAfter(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	sessions := make(chan Session, 10)
+
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}), WithSessionOpenHook(func(s Session) {
+		sessions <- s
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	var attempts []int
+	initCli := make(chan struct{}, 10)
+	client, err := Join(addr,
+		WithSessionOpenHook(func(s Session) {
+			initCli <- struct{}{}
+		}),
+		WithRetryResetAfter(time.Hour),
+		WithRetryPolicy(func(min, max time.Duration, attemptNum int) time.Duration {
+			attempts = append(attempts, attemptNum)
+			return time.Millisecond
+		}),
+	)
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+
+	// the unhealthy session drops continue the backoff
+	for i := 0; i < 3; i++ {
+		<-initCli
+		sess := <-sessions
+		assert.Nil(t, sess.Close())
+	}
+	<-initCli
+	<-sessions
+	assert.Equal(t, []int{0, 1, 2}, attempts)
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
@@ -0,0 +1,444 @@
+package wirenet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mediabuyerbot/go-wirenet/pb"
+)
+
+// RPCStreamName is the name of the stream of the RPC calls. See Wire.Method(), Call().
+// The calls of the session are multiplexed over one stream, so each call does not open a new stream.
+const RPCStreamName = "wirenet.rpc"
+
+const codecKey = "codec"
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type rpcMethod struct {
+	fn      reflect.Value
+	reqType reflect.Type
+}
+
+// newRPCMethod returns the method if fn is func(context.Context, *Req) (*Resp, error).
+func newRPCMethod(fn interface{}) (*rpcMethod, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, ErrInvalidMethod
+	}
+	t := v.Type()
+	if t.NumIn() != 2 || t.NumOut() != 2 ||
+		t.In(0) != contextType || t.In(1).Kind() != reflect.Ptr ||
+		t.Out(0).Kind() != reflect.Ptr || t.Out(1) != errorType {
+		return nil, ErrInvalidMethod
+	}
+	return &rpcMethod{
+		fn:      v,
+		reqType: t.In(1).Elem(),
+	}, nil
+}
+
+func (m *rpcMethod) call(ctx context.Context, codec Codec, payload []byte) ([]byte, error) {
+	req := reflect.New(m.reqType)
+	if err := codec.Unmarshal(payload, req.Interface()); err != nil {
+		return nil, &RemoteError{Code: ErrorCodeInvalidArgument, Message: err.Error()}
+	}
+	out := m.fn.Call([]reflect.Value{reflect.ValueOf(ctx), req})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if out[0].IsNil() {
+		return nil, nil
+	}
+	return codec.Marshal(out[0].Interface())
+}
+
+func (w *wire) Method(name string, fn interface{}) error {
+	method, err := newRPCMethod(fn)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.methods[name] = method
+	w.handlers[RPCStreamName] = w.serveRPC
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *wire) findMethod(name string) (*rpcMethod, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	method, ok := w.methods[name]
+	if !ok {
+		return nil, ErrMethodNotFound
+	}
+	return method, nil
+}
+
+// codecByName returns the codec of the RPC calls sent by the peer.
+func (w *wire) codecByName(name string) (Codec, error) {
+	if name == w.rpcCodec.Name() {
+		return w.rpcCodec, nil
+	}
+	for _, codec := range []Codec{JSONCodec, GobCodec, ProtoCodec} {
+		if name == codec.Name() {
+			return codec, nil
+		}
+	}
+	return nil, ErrUnknownCodec
+}
+
+func (w *wire) serveRPC(ctx context.Context, s Stream) {
+	codec, err := w.codecByName(s.Metadata().Get(codecKey))
+	if err != nil {
+		_ = s.CloseWithError(ErrorCodeInvalidArgument, err.Error())
+		return
+	}
+	_ = s.SetReadDeadline(time.Time{})
+	srv := &rpcServer{
+		w:     w,
+		sess:  s.Session().(*session),
+		ms:    NewMessageStream(s, ProtoCodec),
+		codec: codec,
+		calls: make(map[uint64]context.CancelFunc),
+	}
+	srv.serve(ctx)
+}
+
+// rpcServer serves the RPC calls of the stream.
+// The stream is closed when the session is closing and the active calls are completed.
+type rpcServer struct {
+	w       *wire
+	sess    *session
+	ms      MessageStream
+	codec   Codec
+	calls   map[uint64]context.CancelFunc
+	closing bool
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+func (srv *rpcServer) serve(ctx context.Context) {
+	go func() {
+		select {
+		case <-srv.sess.done:
+			srv.drain()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		req := new(pb.RPCRequest)
+		if err := srv.ms.Recv(req); err != nil {
+			break
+		}
+		if req.Cancel {
+			srv.cancel(req.Id)
+			continue
+		}
+		callCtx, cancel := context.WithCancel(ctx)
+		if req.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout))
+		}
+		srv.mu.Lock()
+		srv.calls[req.Id] = cancel
+		srv.mu.Unlock()
+
+		srv.wg.Add(1)
+		go srv.call(callCtx, req)
+	}
+
+	// the responses can not be sent anymore
+	srv.mu.Lock()
+	for _, cancel := range srv.calls {
+		cancel()
+	}
+	srv.mu.Unlock()
+	srv.wg.Wait()
+}
+
+func (srv *rpcServer) call(ctx context.Context, req *pb.RPCRequest) {
+	defer func() {
+		srv.cancel(req.Id)
+		srv.wg.Done()
+	}()
+
+	res := &pb.RPCResponse{Id: req.Id}
+	payload, err := srv.invoke(ctx, req)
+	if err != nil {
+		code, msg := rpcError(err)
+		res.Failed = true
+		res.Code = uint32(code)
+		res.Message = msg
+	} else {
+		res.Payload = payload
+	}
+	if err := srv.ms.Send(res); err != nil {
+		srv.sess.errLog(ctx, err, "send rpc response")
+	}
+}
+
+func (srv *rpcServer) invoke(ctx context.Context, req *pb.RPCRequest) (payload []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			srv.sess.errLog(ctx, fmt.Errorf("recover %v", r), "recover rpc")
+			err = &RemoteError{Code: ErrorCodeInternal, Message: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	method, err := srv.w.findMethod(req.Method)
+	if err != nil {
+		return nil, &RemoteError{Code: ErrorCodeMethodNotFound, Message: err.Error() + ": " + req.Method}
+	}
+	return method.call(ctx, srv.codec, req.Payload)
+}
+
+// cancel cancels the call and closes the stream if the session is closing and there are no active calls.
+func (srv *rpcServer) cancel(id uint64) {
+	srv.mu.Lock()
+	cancel, ok := srv.calls[id]
+	delete(srv.calls, id)
+	idle := srv.closing && len(srv.calls) == 0
+	srv.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	if idle {
+		_ = srv.ms.Close()
+	}
+}
+
+func (srv *rpcServer) drain() {
+	srv.mu.Lock()
+	srv.closing = true
+	idle := len(srv.calls) == 0
+	srv.mu.Unlock()
+	if idle {
+		_ = srv.ms.Close()
+	}
+}
+
+// rpcError returns the code and the message of the error sent to the caller.
+func rpcError(err error) (ErrorCode, string) {
+	switch e := err.(type) {
+	case *RemoteError:
+		return e.Code, e.Message
+	}
+	switch err {
+	case context.DeadlineExceeded:
+		return ErrorCodeDeadlineExceeded, err.Error()
+	case context.Canceled:
+		return ErrorCodeCanceled, err.Error()
+	}
+	return ErrorCodeUnknown, err.Error()
+}
+
+// Call calls the RPC method registered by Wire.Method() on the peer side of the session
+// and decodes the response to resp. The deadline of the context is sent to the method.
+// If the method fails, *RemoteError is returned.
+func Call(ctx context.Context, sess Session, method string, req, resp interface{}) error {
+	s, err := wireSession(sess)
+	if err != nil {
+		return err
+	}
+	if s.IsClosed() {
+		return ErrSessionClosed
+	}
+	return s.rpc.call(ctx, method, req, resp)
+}
+
+// rpcClient sends the RPC calls of the session over one stream.
+// The stream is opened by the first call and reopened after it is broken.
+type rpcClient struct {
+	sess *session
+	conn *rpcConn
+	mu   sync.Mutex
+}
+
+func (c *rpcClient) connect(ctx context.Context) (*rpcConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	codec := c.sess.w.rpcCodec
+	s, err := c.sess.OpenStreamContext(ctx, RPCStreamName,
+		WithStreamMetadata(Metadata{codecKey: codec.Name()}))
+	if err != nil {
+		return nil, err
+	}
+	_ = s.SetReadDeadline(time.Time{})
+	conn := &rpcConn{
+		ms:     NewMessageStream(s, ProtoCodec),
+		codec:  codec,
+		calls:  make(map[uint64]chan *pb.RPCResponse),
+		broken: make(chan struct{}),
+	}
+	c.conn = conn
+	go c.recv(conn)
+	go func() {
+		select {
+		case <-c.sess.done:
+			conn.drain()
+		case <-conn.broken:
+		}
+	}()
+	return conn, nil
+}
+
+// recv delivers the responses until the stream is broken.
+func (c *rpcClient) recv(conn *rpcConn) {
+	var err error
+	for {
+		res := new(pb.RPCResponse)
+		if err = conn.ms.Recv(res); err != nil {
+			break
+		}
+		conn.deliver(res)
+	}
+
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+	conn.fail(err)
+}
+
+func (c *rpcClient) call(ctx context.Context, method string, req, resp interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := conn.codec.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var timeout int64
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = int64(time.Until(deadline))
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	id, resCh, err := conn.register()
+	if err != nil {
+		return err
+	}
+	if err := conn.ms.Send(&pb.RPCRequest{
+		Id:      id,
+		Method:  method,
+		Payload: payload,
+		Timeout: timeout,
+	}); err != nil {
+		conn.unregister(id)
+		return err
+	}
+
+	select {
+	case res, ok := <-resCh:
+		if !ok {
+			return conn.err
+		}
+		if res.Failed {
+			if ctxErr := ctx.Err(); ctxErr != nil && res.Code == uint32(ErrorCodeDeadlineExceeded) {
+				return ctxErr
+			}
+			return &RemoteError{Code: ErrorCode(res.Code), Message: res.Message}
+		}
+		if len(res.Payload) == 0 || resp == nil {
+			return nil
+		}
+		return conn.codec.Unmarshal(res.Payload, resp)
+	case <-ctx.Done():
+		if conn.unregister(id) {
+			_ = conn.ms.Send(&pb.RPCRequest{Id: id, Cancel: true})
+		}
+		return ctx.Err()
+	}
+}
+
+// rpcConn is the stream of the RPC calls with the calls waiting for the response.
+type rpcConn struct {
+	ms      MessageStream
+	codec   Codec
+	seq     uint64
+	calls   map[uint64]chan *pb.RPCResponse
+	closing bool
+	broken  chan struct{}
+	err     error
+	mu      sync.Mutex
+}
+
+func (c *rpcConn) register() (uint64, chan *pb.RPCResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, nil, c.err
+	}
+	if c.closing {
+		return 0, nil, ErrSessionClosed
+	}
+	c.seq++
+	ch := make(chan *pb.RPCResponse, 1)
+	c.calls[c.seq] = ch
+	return c.seq, ch, nil
+}
+
+// unregister removes the call and closes the stream if the session is closing and there are no active calls.
+// Returns a false flag if the call is already completed.
+func (c *rpcConn) unregister(id uint64) bool {
+	c.mu.Lock()
+	_, ok := c.calls[id]
+	delete(c.calls, id)
+	idle := c.closing && len(c.calls) == 0
+	c.mu.Unlock()
+	if idle {
+		_ = c.ms.Close()
+	}
+	return ok
+}
+
+func (c *rpcConn) deliver(res *pb.RPCResponse) {
+	c.mu.Lock()
+	ch, ok := c.calls[res.Id]
+	c.mu.Unlock()
+	if ok && c.unregister(res.Id) {
+		ch <- res
+	}
+}
+
+func (c *rpcConn) drain() {
+	c.mu.Lock()
+	c.closing = true
+	idle := len(c.calls) == 0
+	c.mu.Unlock()
+	if idle {
+		_ = c.ms.Close()
+	}
+}
+
+func (c *rpcConn) fail(err error) {
+	if err == nil || err == io.EOF {
+		err = ErrStreamClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	for id, ch := range c.calls {
+		delete(c.calls, id)
+		close(ch)
+	}
+	close(c.broken)
+	c.mu.Unlock()
+	_ = c.ms.Close()
+}
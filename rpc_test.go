@@ -0,0 +1,114 @@
+package wirenet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sumArgs struct {
+	A, B int
+}
+
+type sumResult struct {
+	Sum int
+}
+
+func TestWire_Method(t *testing.T) {
+	w, err := Mount(":0")
+	assert.Nil(t, err)
+	for _, fn := range []interface{}{
+		nil,
+		1,
+		func(ctx context.Context, args sumArgs) (*sumResult, error) { return nil, nil },
+		func(ctx context.Context, args *sumArgs) (sumResult, error) { return sumResult{}, nil },
+		func(args *sumArgs) (*sumResult, error) { return nil, nil },
+		func(ctx context.Context, args *sumArgs) *sumResult { return nil },
+	} {
+		assert.Equal(t, ErrInvalidMethod, w.Method("math.Sum", fn))
+	}
+	assert.Nil(t, w.Method("math.Sum", func(ctx context.Context, args *sumArgs) (*sumResult, error) {
+		return &sumResult{Sum: args.A + args.B}, nil
+	}))
+	_, err = w.(*wire).findHandler(RPCStreamName)
+	assert.Nil(t, err)
+}
+
+func TestCall(t *testing.T) {
+	canceled := make(chan error, 2)
+	methods := func(w *wire) {
+		assert.Nil(t, w.Method("math.Sum", func(ctx context.Context, args *sumArgs) (*sumResult, error) {
+			return &sumResult{Sum: args.A + args.B}, nil
+		}))
+		assert.Nil(t, w.Method("math.Fail", func(ctx context.Context, args *sumArgs) (*sumResult, error) {
+			return nil, &RemoteError{Code: 42, Message: "bad args"}
+		}))
+		assert.Nil(t, w.Method("math.Panic", func(ctx context.Context, args *sumArgs) (*sumResult, error) {
+			panic("boom")
+		}))
+		assert.Nil(t, w.Method("math.Slow", func(ctx context.Context, args *sumArgs) (*sumResult, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil, ctx.Err()
+		}))
+	}
+	sess, closeWires := connectStreams(t, nil, methods)
+	defer closeWires()
+
+	// the calls are multiplexed over one stream
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var res sumResult
+			assert.Nil(t, Call(context.Background(), sess, "math.Sum", &sumArgs{A: i, B: 1}, &res))
+			assert.Equal(t, i+1, res.Sum)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, sess.(*session).activeStreamCounter())
+
+	var res sumResult
+	err := Call(context.Background(), sess, "math.Fail", &sumArgs{}, &res)
+	assert.Equal(t, &RemoteError{Code: 42, Message: "bad args"}, err)
+
+	err = Call(context.Background(), sess, "math.Unknown", &sumArgs{}, &res)
+	assert.Equal(t, &RemoteError{Code: ErrorCodeMethodNotFound, Message: "wirenet: rpc method not found: math.Unknown"}, err)
+
+	err = Call(context.Background(), sess, "math.Panic", &sumArgs{}, &res)
+	assert.Equal(t, &RemoteError{Code: ErrorCodeInternal, Message: "panic: boom"}, err)
+
+	// the deadline is sent to the method
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = Call(ctx, sess, "math.Slow", &sumArgs{}, &res)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, context.DeadlineExceeded, <-canceled)
+
+	// the cancellation is sent to the method
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	err = Call(ctx, sess, "math.Slow", &sumArgs{}, &res)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, <-canceled)
+
+	// the stream is still usable
+	assert.Nil(t, Call(context.Background(), sess, "math.Sum", &sumArgs{A: 2, B: 3}, &res))
+	assert.Equal(t, 5, res.Sum)
+	assert.Equal(t, 1, sess.(*session).activeStreamCounter())
+}
+
+func TestCall_UnknownSession(t *testing.T) {
+	// the session implemented outside of the wire
+	var sess struct{ Session }
+	var res sumResult
+	assert.Equal(t, ErrUnknownSession, Call(context.Background(), sess, "math.Sum", &sumArgs{}, &res))
+	_, err := CallStream(context.Background(), sess, "quotes.Feed", nil)
+	assert.Equal(t, ErrUnknownSession, err)
+	_, err = ForwardLocal(sess, "127.0.0.1:0", "upper")
+	assert.Equal(t, ErrUnknownSession, err)
+}
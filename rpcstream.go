@@ -0,0 +1,280 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RPCStreamingName is the name of the streams of the streaming RPC calls.
+// See Wire.StreamMethod(), CallStream(), CallBidiStream().
+// Each call opens a new stream, so the slow reader holds back the writer by the window of the stream.
+const RPCStreamingName = "wirenet.rpc.stream"
+
+const (
+	methodKey  = "method"
+	timeoutKey = "timeout"
+)
+
+var serverStreamType = reflect.TypeOf((*ServerStream)(nil)).Elem()
+
+// ServerStream is used by the streaming RPC method to exchange the messages with the caller.
+type ServerStream interface {
+
+	// Send sends the message to the caller.
+	Send(v interface{}) error
+
+	// Recv receives the message of the caller, io.EOF is returned after the caller calls CloseSend().
+	Recv(v interface{}) error
+}
+
+// ClientStream is used by the caller of the streaming RPC method.
+// The stream is closed when the context of the call is done or Next() returns an error.
+type ClientStream interface {
+
+	// Next receives the next message of the method and decodes it to v.
+	// Returns io.EOF when the method returns, or *RemoteError when the method fails.
+	// If the context is done, the call is canceled and the context error is returned.
+	Next(ctx context.Context, v interface{}) error
+
+	// Send sends the message to the method.
+	Send(v interface{}) error
+
+	// CloseSend closes the sending side, the method receives io.EOF.
+	CloseSend() error
+
+	// Close cancels the call, the context of the method is canceled.
+	Close() error
+}
+
+type rpcStreamMethod struct {
+	fn      reflect.Value
+	reqType reflect.Type
+}
+
+// newRPCStreamMethod returns the method if fn is
+// func(context.Context, *Req, ServerStream) error or func(context.Context, ServerStream) error.
+func newRPCStreamMethod(fn interface{}) (*rpcStreamMethod, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, ErrInvalidMethod
+	}
+	t := v.Type()
+	if t.NumOut() != 1 || t.Out(0) != errorType || t.NumIn() < 2 || t.NumIn() > 3 ||
+		t.In(0) != contextType || t.In(t.NumIn()-1) != serverStreamType {
+		return nil, ErrInvalidMethod
+	}
+	method := &rpcStreamMethod{fn: v}
+	if t.NumIn() == 3 {
+		if t.In(1).Kind() != reflect.Ptr {
+			return nil, ErrInvalidMethod
+		}
+		method.reqType = t.In(1).Elem()
+	}
+	return method, nil
+}
+
+func (m *rpcStreamMethod) call(ctx context.Context, ss MessageStream) error {
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if m.reqType != nil {
+		req := reflect.New(m.reqType)
+		if err := ss.Recv(req.Interface()); err != nil {
+			return &RemoteError{Code: ErrorCodeInvalidArgument, Message: err.Error()}
+		}
+		args = append(args, req)
+
+		// the rest of the caller side is read, so the peer close is noticed while the method is sending
+		rest := ss.Stream().Reader()
+		go func() {
+			_, _ = io.Copy(ioutil.Discard, rest)
+		}()
+		ss = sendOnlyStream{ss}
+	}
+	args = append(args, reflect.ValueOf(ss))
+	out := m.fn.Call(args)
+	err, _ := out[0].Interface().(error)
+	return err
+}
+
+// sendOnlyStream is the stream of the server-streaming method, the request is the only message of the caller.
+type sendOnlyStream struct {
+	MessageStream
+}
+
+func (sendOnlyStream) Recv(v interface{}) error {
+	return io.EOF
+}
+
+func (w *wire) StreamMethod(name string, fn interface{}) error {
+	method, err := newRPCStreamMethod(fn)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.streamMethods[name] = method
+	w.handlers[RPCStreamingName] = w.serveStreamRPC
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *wire) findStreamMethod(name string) (*rpcStreamMethod, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	method, ok := w.streamMethods[name]
+	if !ok {
+		return nil, ErrMethodNotFound
+	}
+	return method, nil
+}
+
+func (w *wire) serveStreamRPC(ctx context.Context, s Stream) {
+	md := s.Metadata()
+	codec, err := w.codecByName(md.Get(codecKey))
+	if err != nil {
+		_ = s.CloseWithError(ErrorCodeInvalidArgument, err.Error())
+		return
+	}
+	name := md.Get(methodKey)
+	method, err := w.findStreamMethod(name)
+	if err != nil {
+		_ = s.CloseWithError(ErrorCodeMethodNotFound, err.Error()+": "+name)
+		return
+	}
+	if timeout, err := strconv.ParseInt(md.Get(timeoutKey), 10, 64); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout))
+		defer cancel()
+	}
+
+	// the blocked reads and writes of the method are aborted when the call is canceled
+	_ = s.SetReadDeadline(time.Time{})
+	ms := NewMessageStream(s, codec)
+	stop := watchContext(ctx, s)
+	if err := stop(method.call(ctx, ms)); err != nil {
+		code, msg := rpcError(err)
+		_ = s.CloseWithError(code, msg)
+		return
+	}
+	_ = ms.CloseSend()
+}
+
+// CallStream calls the server-streaming RPC method registered by Wire.StreamMethod() on the peer side
+// of the session with the request and returns the stream of the responses.
+// The call is canceled when the context is done.
+func CallStream(ctx context.Context, sess Session, method string, req interface{}) (ClientStream, error) {
+	s, err := wireSession(sess)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := s.openClientStream(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.Send(req); err != nil {
+		_ = cs.Close()
+		return nil, err
+	}
+	if err := cs.CloseSend(); err != nil {
+		_ = cs.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
+// CallBidiStream opens the bidirectional streaming RPC call registered by Wire.StreamMethod()
+// on the peer side of the session. The call is canceled when the context is done.
+func CallBidiStream(ctx context.Context, sess Session, method string) (ClientStream, error) {
+	s, err := wireSession(sess)
+	if err != nil {
+		return nil, err
+	}
+	return s.openClientStream(ctx, method)
+}
+
+func (s *session) openClientStream(ctx context.Context, method string) (*clientStream, error) {
+	codec := s.w.rpcCodec
+	md := Metadata{
+		codecKey:  codec.Name(),
+		methodKey: method,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		md[timeoutKey] = strconv.FormatInt(int64(timeout), 10)
+	}
+	st, err := s.OpenStreamContext(ctx, RPCStreamingName, WithStreamMetadata(md))
+	if err != nil {
+		return nil, err
+	}
+	_ = st.SetReadDeadline(time.Time{})
+	cs := &clientStream{
+		ctx:    ctx,
+		stream: st,
+		ms:     NewMessageStream(st, codec),
+		done:   make(chan struct{}),
+	}
+	go cs.watch()
+	return cs, nil
+}
+
+type clientStream struct {
+	ctx    context.Context
+	stream Stream
+	ms     MessageStream
+	done   chan struct{}
+	once   sync.Once
+}
+
+// watch closes the stream when the context of the call is done.
+func (c *clientStream) watch() {
+	select {
+	case <-c.ctx.Done():
+		_ = c.Close()
+	case <-c.done:
+	}
+}
+
+func (c *clientStream) Next(ctx context.Context, v interface{}) (err error) {
+	if ctx.Done() == nil {
+		err = c.ms.Recv(v)
+	} else {
+		stop := watchContext(ctx, c.stream)
+		err = stop(c.ms.Recv(v))
+	}
+	if err == nil {
+		return nil
+	}
+	_ = c.Close()
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+func (c *clientStream) Send(v interface{}) error {
+	if err := c.ms.Send(v); err != nil {
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *clientStream) CloseSend() error {
+	return c.ms.CloseSend()
+}
+
+func (c *clientStream) Close() (err error) {
+	c.once.Do(func() {
+		close(c.done)
+		err = c.ms.Close()
+	})
+	return err
+}
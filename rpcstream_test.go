@@ -0,0 +1,154 @@
+package wirenet
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type feedArgs struct {
+	Count int
+}
+
+type quote struct {
+	Seq int
+}
+
+func TestWire_StreamMethod(t *testing.T) {
+	w, err := Mount(":0")
+	assert.Nil(t, err)
+	for _, fn := range []interface{}{
+		nil,
+		func(ctx context.Context, args feedArgs, stream ServerStream) error { return nil },
+		func(ctx context.Context, args *feedArgs) error { return nil },
+		func(ctx context.Context, stream ServerStream) {},
+		func(stream ServerStream) error { return nil },
+	} {
+		assert.Equal(t, ErrInvalidMethod, w.StreamMethod("quotes.Feed", fn))
+	}
+	assert.Nil(t, w.StreamMethod("quotes.Feed", func(ctx context.Context, args *feedArgs, stream ServerStream) error {
+		return nil
+	}))
+	assert.Nil(t, w.StreamMethod("echo.Upper", func(ctx context.Context, stream ServerStream) error {
+		return nil
+	}))
+}
+
+func TestCallStream(t *testing.T) {
+	canceled := make(chan error, 1)
+	methods := func(w *wire) {
+		assert.Nil(t, w.StreamMethod("quotes.Feed", func(ctx context.Context, args *feedArgs, stream ServerStream) error {
+			for i := 0; i < args.Count; i++ {
+				if err := stream.Send(&quote{Seq: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		assert.Nil(t, w.StreamMethod("quotes.Fail", func(ctx context.Context, args *feedArgs, stream ServerStream) error {
+			assert.Nil(t, stream.Send(&quote{Seq: 1}))
+			return &RemoteError{Code: 7, Message: "feed closed"}
+		}))
+		assert.Nil(t, w.StreamMethod("quotes.Infinite", func(ctx context.Context, args *feedArgs, stream ServerStream) error {
+			for i := 0; ; i++ {
+				if err := stream.Send(&quote{Seq: i}); err != nil {
+					canceled <- ctx.Err()
+					return err
+				}
+			}
+		}))
+		assert.Nil(t, w.StreamMethod("quotes.Slow", func(ctx context.Context, args *feedArgs, stream ServerStream) error {
+			assert.Nil(t, stream.Send(&quote{Seq: 1}))
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return ctx.Err()
+		}))
+	}
+	sess, closeWires := connectStreams(t, nil, methods)
+	defer closeWires()
+
+	// the messages are received until the method returns
+	stream, err := CallStream(context.Background(), sess, "quotes.Feed", &feedArgs{Count: 100})
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		var q quote
+		assert.Nil(t, stream.Next(context.Background(), &q))
+		assert.Equal(t, i, q.Seq)
+	}
+	assert.Equal(t, io.EOF, stream.Next(context.Background(), &quote{}))
+
+	// the error of the method
+	stream, err = CallStream(context.Background(), sess, "quotes.Fail", &feedArgs{})
+	assert.Nil(t, err)
+	var q quote
+	assert.Nil(t, stream.Next(context.Background(), &q))
+	assert.Equal(t, &RemoteError{Code: 7, Message: "feed closed"}, stream.Next(context.Background(), &q))
+
+	stream, err = CallStream(context.Background(), sess, "quotes.Unknown", &feedArgs{})
+	assert.Nil(t, err)
+	err = stream.Next(context.Background(), &q)
+	assert.Equal(t, &RemoteError{Code: ErrorCodeMethodNotFound, Message: "wirenet: rpc method not found: quotes.Unknown"}, err)
+
+	// the caller cancels the call, the writer of the method is blocked by the window of the stream
+	stream, err = CallStream(context.Background(), sess, "quotes.Infinite", &feedArgs{})
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Next(context.Background(), &q))
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, stream.Close())
+	assert.Equal(t, context.Canceled, <-canceled)
+
+	// the deadline of the call is sent to the method
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	stream, err = CallStream(ctx, sess, "quotes.Slow", &feedArgs{})
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Next(ctx, &q))
+	assert.Equal(t, context.DeadlineExceeded, stream.Next(ctx, &q))
+	// the method is canceled by the deadline or by the caller closing the stream at the deadline
+	assert.NotNil(t, <-canceled)
+
+	// the call is not canceled when the context of Next() is done
+	stream, err = CallStream(context.Background(), sess, "quotes.Feed", &feedArgs{Count: 1})
+	assert.Nil(t, err)
+	nextCtx, nextCancel := context.WithCancel(context.Background())
+	nextCancel()
+	assert.Equal(t, context.Canceled, stream.Next(nextCtx, &q))
+}
+
+func TestCallBidiStream(t *testing.T) {
+	methods := func(w *wire) {
+		assert.Nil(t, w.StreamMethod("echo.Upper", func(ctx context.Context, stream ServerStream) error {
+			for {
+				var msg string
+				if err := stream.Recv(&msg); err != nil {
+					if err == io.EOF {
+						return nil
+					}
+					return err
+				}
+				if err := stream.Send(strings.ToUpper(msg)); err != nil {
+					return err
+				}
+			}
+		}))
+	}
+	sess, closeWires := connectStreams(t, nil, methods)
+	defer closeWires()
+
+	stream, err := CallBidiStream(context.Background(), sess, "echo.Upper")
+	assert.Nil(t, err)
+	for _, msg := range []string{"a", "b", "c"} {
+		assert.Nil(t, stream.Send(msg))
+		var upper string
+		assert.Nil(t, stream.Next(context.Background(), &upper))
+		assert.Equal(t, strings.ToUpper(msg), upper)
+	}
+	assert.Nil(t, stream.CloseSend())
+	var msg string
+	assert.Equal(t, io.EOF, stream.Next(context.Background(), &msg))
+	assert.Nil(t, stream.Close())
+}
@@ -32,6 +32,11 @@ type Session interface {
 	// After the named stream is successfully opened, an authentication frame is sent.
 	OpenStream(name string) (Stream, error)
 
+	// OpenStreamContext opens a named stream with the options and returns it.
+	// The handshake is aborted when the context is done.
+	// The metadata set by WithStreamMetadata() is delivered to the handler of the stream.
+	OpenStreamContext(ctx context.Context, name string, opts ...StreamOption) (Stream, error)
+
 	// Identification returns some information specified by the user on the client side using WithIdentification().
 	Identification() Identification
 
@@ -45,28 +50,52 @@ type session struct {
 	w              *wire
 	streamNames    []string
 	closed         bool
-	closeCh        chan chan error
+	closeCh        chan *closeRequest
 	activeStreams  int
 	streams        map[uuid.UUID]Stream
 	mu             sync.RWMutex
 	timeoutDur     time.Duration
 	identification Identification
+	ctx            context.Context
+	ticket         []byte
+	detached       bool
+	expiry         *time.Timer
+	done           chan struct{}
+	rpc            *rpcClient
+}
+
+// wireSession returns the session opened by the wire, the RPC calls and the forwardings are served by its wire.
+func wireSession(sess Session) (*session, error) {
+	s, ok := sess.(*session)
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+	return s, nil
 }
 
-func openSession(sid uuid.UUID, id Identification, conn *yamux.Session, w *wire, streamNames []string) {
+func openSession(sid uuid.UUID, id Identification, conn *yamux.Session, w *wire, streamNames []string, ticket []byte) {
 	sess := &session{
 		id:             sid,
 		conn:           conn,
 		w:              w,
 		streamNames:    streamNames,
-		closeCh:        make(chan chan error),
+		closeCh:        make(chan *closeRequest),
 		streams:        make(map[uuid.UUID]Stream),
 		timeoutDur:     w.sessCloseTimeout,
 		identification: id,
+		ticket:         ticket,
+		done:           make(chan struct{}),
 	}
+	sess.rpc = &rpcClient{sess: sess}
 	go sess.open()
 }
 
+func (s *session) connection() *yamux.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conn
+}
+
 func (s *session) Identification() Identification {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -93,6 +122,9 @@ func (s *session) registerStream(stream Stream) {
 func (s *session) unregisterStream(stream Stream) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.streams[stream.ID()]; !ok {
+		return
+	}
 	delete(s.streams, stream.ID())
 	if s.activeStreams > 0 {
 		s.activeStreams--
@@ -105,50 +137,68 @@ func (s *session) activeStreamCounter() int {
 	return s.activeStreams
 }
 
+// closeRequest is used to close the session gracefully until the context is done.
+type closeRequest struct {
+	ctx   context.Context
+	cut   []string
+	errCh chan error
+}
+
 func (s *session) shutdown() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
-		for {
-			errCh, ok := <-s.closeCh
-			if !ok {
-				return
-			}
+		req, ok := <-s.closeCh
+		if !ok {
+			return
+		}
 
-			timeout := time.Now().Add(s.timeoutDur)
-			for {
-				if s.activeStreamCounter() <= 0 {
-					break
-				}
-
-				if timeout.Unix() <= time.Now().Unix() {
-					for _, stream := range s.streams {
-						stream.Close()
-					}
-					continue
-				}
-				time.Sleep(300 * time.Millisecond)
+		for s.activeStreamCounter() > 0 {
+			select {
+			case <-req.ctx.Done():
+				req.cut = s.closeStreams()
+			case <-time.After(300 * time.Millisecond):
 			}
+		}
 
-			cancel()
+		cancel()
 
-			var closeErr error
-			if !s.conn.IsClosed() {
-				closeErr = s.conn.Close()
-			}
-			errCh <- closeErr
-			close(errCh)
-			break
+		var closeErr error
+		if conn := s.connection(); !conn.IsClosed() {
+			closeErr = conn.Close()
 		}
+		req.errCh <- closeErr
+		close(req.errCh)
 	}()
 	return ctx
 }
 
-func (s *session) validateStreamName(streamName string) (err error) {
+// closeStreams closes all active streams and returns their names.
+func (s *session) closeStreams() []string {
+	s.mu.RLock()
+	streams := make([]Stream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mu.RUnlock()
+
+	names := make([]string, 0, len(streams))
+	for _, stream := range streams {
+		names = append(names, stream.Name())
+		_ = stream.Close()
+	}
+	return names
+}
+
+func (s *session) validateStreamName(streamName string, md Metadata) (err error) {
 	isHubMode := s.w.isHubMode() && !s.w.role.IsClientSide()
 	if isHubMode {
-		_, err = s.w.findSession(streamName)
-		if err == ErrSessionNotFound {
+		var peer Identification
+		if peer, _, err = streamPeer(md); err != nil {
+			return err
+		}
+		_, err = s.allowedProviders(peer, streamName)
+		if err == ErrSessionNotFound && len(peer) == 0 {
 			_, err = s.w.findHandler(streamName)
 		}
 	} else {
@@ -169,22 +219,27 @@ func (s *session) validateToken(streamName string, token []byte) (err error) {
 func (s *session) readFrame(conn *yamux.Stream) (frm frame, err error) {
 	frm, err = recvFrame(conn, func(f frame) error {
 		command := f.Command()
-		if err := s.validateToken(command, f.Payload()); err != nil {
+		token, md, err := streamRequest(f)
+		if err != nil {
+			return err
+		}
+		if err := s.validateToken(command, token); err != nil {
 			return err
 		}
-		return s.validateStreamName(command)
+		return s.validateStreamName(command, md)
 	})
 	return frm, err
 }
 
 func (s *session) errLog(ctx context.Context, err error, op string) {
+	conn := s.connection()
 	opErr := &OpError{
 		Op:             op,
 		Err:            err,
 		SessionID:      s.id,
 		Identification: s.identification,
-		RemoteAddr:     s.conn.RemoteAddr(),
-		LocalAddr:      s.conn.LocalAddr(),
+		RemoteAddr:     conn.RemoteAddr(),
+		LocalAddr:      conn.LocalAddr(),
 	}
 	s.w.errorHandler(ctx, opErr)
 }
@@ -203,27 +258,33 @@ func (s *session) dispatchStream(ctx context.Context, conn *yamux.Stream) {
 	conn.Shrink()
 
 	streamName := frm.Command()
+	_, md, _ := streamRequest(frm)
 	isHubMode := s.w.isHubMode() && !s.w.role.IsClientSide()
 	if isHubMode {
-		err = s.serveHub(ctx, streamName, conn)
+		err = s.serveHub(ctx, streamName, md, conn)
 		if err == ErrSessionNotFound {
-			err = s.serve(ctx, streamName, conn)
+			err = s.serve(ctx, streamName, md, conn)
 		}
 
 	} else {
-		err = s.serve(ctx, streamName, conn)
+		err = s.serve(ctx, streamName, md, conn)
 	}
 	if err != nil {
 		s.errLog(ctx, err, "serve stream")
 	}
 }
 
-func (s *session) serveHub(_ context.Context, streamName string, conn *yamux.Stream) error {
-	sess, err := s.w.findSession(streamName)
+func (s *session) serveHub(ctx context.Context, streamName string, md Metadata, conn *yamux.Stream) error {
+	peer, md, err := streamPeer(md)
 	if err != nil {
 		return err
 	}
-	dst, err := sess.OpenStream(streamName)
+	providers, err := s.allowedProviders(peer, streamName)
+	if err != nil {
+		return err
+	}
+	sess := s.w.balance(streamName, md.Get(BalanceKeyHeader), providers)
+	dst, err := sess.OpenStreamContext(ctx, streamName, WithStreamMetadata(md))
 	if err != nil {
 		return err
 	}
@@ -231,25 +292,30 @@ func (s *session) serveHub(_ context.Context, streamName string, conn *yamux.Str
 		conn.Close()
 		dst.Close()
 	}()
-	dstConn := dst.(*stream).conn
+	dstConn := dst.(*stream).conn.Stream
 	go func() {
 		_ = pipe(dstConn, conn)
 	}()
 	return pipe(conn, dstConn)
 }
 
-func (s *session) serve(ctx context.Context, streamName string, conn *yamux.Stream) error {
+func (s *session) serve(ctx context.Context, streamName string, md Metadata, conn *yamux.Stream) error {
+	var stream *stream
 	defer func() {
 		if err := recover(); err != nil {
 			s.errLog(ctx, fmt.Errorf("recover %v", err), "recover stream")
+			// the caller receives the panic as the remote error
+			if stream != nil && !stream.IsClosed() {
+				_ = stream.CloseWithError(ErrorCodeInternal, fmt.Sprintf("panic: %v", err))
+			}
 		}
 	}()
 	handler, err := s.w.findHandler(streamName)
 	if err != nil {
 		return err
 	}
-	stream := openStream(s, streamName, conn)
-	handler(ctx, stream)
+	stream = openStream(s, streamName, md, conn)
+	handler(stream.context(ctx), stream)
 	if !stream.IsClosed() {
 		_ = stream.Close()
 	}
@@ -257,18 +323,20 @@ func (s *session) serve(ctx context.Context, streamName string, conn *yamux.Stre
 }
 
 func (s *session) open() {
-	defer func() {
-		_ = s.Close()
-	}()
-
-	ctx := s.shutdown()
+	s.ctx = s.shutdown()
 	s.w.registerSession(s)
 	go s.w.openSessHook(s)
+	s.accept()
+}
 
+// accept serves the streams of the current connection.
+// If the connection drops and the session is resumable, the session is detached, otherwise it is closed.
+func (s *session) accept() {
+	sessConn := s.connection()
 	for {
-		conn, err := s.conn.AcceptStream()
+		conn, err := sessConn.AcceptStream()
 		if err != nil {
-			return
+			break
 		}
 
 		if s.IsClosed() || s.w.isClosed() {
@@ -276,7 +344,15 @@ func (s *session) open() {
 			continue
 		}
 
-		go s.dispatchStream(ctx, conn)
+		go s.dispatchStream(s.ctx, conn)
+	}
+
+	if !s.detach(sessConn) {
+		_ = s.Close()
+		return
+	}
+	if s.w.role.IsClientSide() {
+		s.w.detachSession(s)
 	}
 }
 
@@ -297,36 +373,73 @@ func (s *session) CloseWire() error {
 }
 
 func (s *session) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeoutDur)
+	defer cancel()
+	_, err := s.close(ctx)
+	return err
+}
+
+// close closes gracefully the session until the context is done,
+// then closes the active streams and returns their names.
+func (s *session) close(ctx context.Context) (cut []string, err error) {
 	if s.IsClosed() {
-		return ErrSessionClosed
+		return nil, ErrSessionClosed
 	}
 
 	s.mu.Lock()
 	s.closed = true
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
 	s.mu.Unlock()
 
-	errCh := make(chan error)
-	s.closeCh <- errCh
-	closeErr := <-errCh
+	req := &closeRequest{
+		ctx:   ctx,
+		errCh: make(chan error),
+	}
+	s.closeCh <- req
+	closeErr := <-req.errCh
 
 	s.w.unregisterSession(s)
 	go s.w.closeSessHook(s)
 
-	return closeErr
+	return req.cut, closeErr
 }
 
 func (s *session) OpenStream(name string) (Stream, error) {
+	return s.OpenStreamContext(context.Background(), name)
+}
+
+func (s *session) OpenStreamContext(ctx context.Context, name string, opts ...StreamOption) (Stream, error) {
 	if s.IsClosed() {
 		return nil, ErrSessionClosed
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	conn, err := s.conn.OpenStream()
+	options := new(streamOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	typ, payload, err := streamFrame(s.w.token, options.metadata)
 	if err != nil {
 		return nil, err
 	}
 
-	frm, err := sendFrame(name, permFrameTyp, s.w.token, conn)
+	conn, err := s.connection().OpenStream()
 	if err != nil {
+		return nil, err
+	}
+
+	stop := watchContext(ctx, conn)
+	frm, err := sendFrame(name, typ, payload, conn)
+	if err = stop(err); err != nil {
 		conn.Close()
 		return nil, err
 	}
@@ -336,7 +449,7 @@ func (s *session) OpenStream(name string) (Stream, error) {
 	}
 
 	conn.Shrink()
-	stream := openStream(s, name, conn)
+	stream := openStream(s, name, options.metadata, conn)
 
 	return stream, nil
 }
@@ -0,0 +1,125 @@
+package socks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mediabuyerbot/go-wirenet"
+)
+
+// DefaultDialTimeout is the timeout of the connection to the destination.
+const DefaultDialTimeout = 10 * time.Second
+
+// ServeEgress serves the connections of the SOCKS5 server of the peer on the remote side.
+// The destinations are dialed by this side according to the rules.
+// The listener of StreamName is registered on the wire, so the egress is served before Connect().
+func ServeEgress(w wirenet.Wire, rules Rules) io.Closer {
+	listener := wirenet.Listen(w, StreamName)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go egress(conn, rules)
+		}
+	}()
+	return listener
+}
+
+func egress(conn net.Conn, rules Rules) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(DefaultDialTimeout))
+	addr, err := readAddr(conn)
+	if err != nil {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	target, rep := dial(addr, rules)
+	if _, err := conn.Write([]byte{rep}); err != nil || rep != repSucceeded {
+		if target != nil {
+			_ = target.Close()
+		}
+		return
+	}
+	wirenet.Relay(conn, target)
+}
+
+// dial resolves the destination and dials the first allowed address.
+func dial(addr string, rules Rules) (net.Conn, byte) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, repAddressNotSupported
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDialTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, repHostUnreachable
+	}
+	rep := byte(repNotAllowed)
+	var d net.Dialer
+	for _, ip := range ips {
+		if !rules.Allowed(host, ip.IP, port) {
+			continue
+		}
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, repSucceeded
+		}
+		rep = dialErrorReply(err)
+	}
+	return nil, rep
+}
+
+func dialErrorReply(err error) byte {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ECONNREFUSED:
+			return repConnectionRefused
+		case syscall.ENETUNREACH:
+			return repNetworkUnreachable
+		case syscall.EHOSTUNREACH:
+			return repHostUnreachable
+		}
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return repHostUnreachable
+	}
+	return repGeneralFailure
+}
+
+// writeAddr writes the destination of the connection to the egress.
+func writeAddr(w io.Writer, addr string) error {
+	if len(addr) > 0xff {
+		return errAddrTooLong
+	}
+	_, err := w.Write(append([]byte{byte(len(addr))}, addr...))
+	return err
+}
+
+func readAddr(r io.Reader) (string, error) {
+	size := make([]byte, 1)
+	if _, err := io.ReadFull(r, size); err != nil {
+		return "", err
+	}
+	addr := make([]byte, size[0])
+	if _, err := io.ReadFull(r, addr); err != nil {
+		return "", err
+	}
+	return string(addr), nil
+}
+
+func portString(port uint16) string {
+	return strconv.Itoa(int(port))
+}
@@ -0,0 +1,63 @@
+package socks
+
+import (
+	"net"
+	"strings"
+)
+
+// Rules is the allow and deny lists of the destinations.
+// The destination is denied if it matches the deny list,
+// or the allow list is not empty and the destination does not match it.
+//
+// The pattern is the host with the optional port:
+// an IP address (10.0.0.5), a network (10.0.0.0/8), a host name (db.internal),
+// a wildcard host name (*.internal) or any host (*), for example 10.0.0.0/8:5432.
+// The host names are resolved, so the networks are matched with the resolved IP addresses.
+type Rules struct {
+	Allow []string
+	Deny  []string
+}
+
+// Allowed returns a true flag if the destination is allowed, otherwise returns a false flag.
+func (r Rules) Allowed(host string, ip net.IP, port string) bool {
+	if matchAny(r.Deny, host, ip, port) {
+		return false
+	}
+	if len(r.Allow) == 0 {
+		return true
+	}
+	return matchAny(r.Allow, host, ip, port)
+}
+
+func matchAny(patterns []string, host string, ip net.IP, port string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, host, ip, port) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, host string, ip net.IP, port string) bool {
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		if p != port {
+			return false
+		}
+		pattern = h
+	}
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		return ip != nil && network.Contains(ip)
+	}
+	if patternIP := net.ParseIP(pattern); patternIP != nil {
+		return ip != nil && patternIP.Equal(ip)
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	pattern = strings.ToLower(pattern)
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
@@ -0,0 +1,36 @@
+package socks
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules_Allowed(t *testing.T) {
+	rules := Rules{
+		Allow: []string{"10.0.0.0/8", "*.internal", "db.local:5432", "192.168.1.5"},
+		Deny:  []string{"10.0.0.1", "secret.internal", "*:25"},
+	}
+	testCases := []struct {
+		host    string
+		ip      string
+		port    string
+		allowed bool
+	}{
+		{host: "10.1.2.3", ip: "10.1.2.3", port: "80", allowed: true},
+		{host: "10.0.0.1", ip: "10.0.0.1", port: "80", allowed: false},
+		{host: "10.1.2.3", ip: "10.1.2.3", port: "25", allowed: false},
+		{host: "api.internal", ip: "172.16.0.1", port: "443", allowed: true},
+		{host: "API.Internal.", ip: "172.16.0.1", port: "443", allowed: true},
+		{host: "secret.internal", ip: "172.16.0.2", port: "443", allowed: false},
+		{host: "db.local", ip: "172.16.0.3", port: "5432", allowed: true},
+		{host: "db.local", ip: "172.16.0.3", port: "5433", allowed: false},
+		{host: "example.com", ip: "192.168.1.5", port: "80", allowed: true},
+		{host: "example.com", ip: "93.184.216.34", port: "80", allowed: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.allowed, rules.Allowed(tc.host, net.ParseIP(tc.ip), tc.port), tc.host+" "+tc.ip+":"+tc.port)
+	}
+	assert.True(t, Rules{}.Allowed("example.com", net.ParseIP("93.184.216.34"), "80"))
+}
@@ -0,0 +1,355 @@
+// Package socks provides the SOCKS5 server which egresses through the remote session.
+// Each CONNECT request opens the stream on the session selected by the Identification,
+// and the remote side dials the destination with ServeEgress().
+package socks
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/mediabuyerbot/go-wirenet"
+)
+
+// StreamName is the name of the stream of the SOCKS5 connections.
+const StreamName = "wirenet.socks"
+
+const (
+	socks5Version = 0x05
+
+	authNone         = 0x00
+	authPassword     = 0x02
+	authNoAcceptable = 0xff
+	authVersion      = 0x01
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	repSucceeded           = 0x00
+	repGeneralFailure      = 0x01
+	repNotAllowed          = 0x02
+	repNetworkUnreachable  = 0x03
+	repHostUnreachable     = 0x04
+	repConnectionRefused   = 0x05
+	repCommandNotSupported = 0x07
+	repAddressNotSupported = 0x08
+)
+
+var (
+	// ErrNoSession is returned when the session of the CONNECT request is not found.
+	ErrNoSession = errors.New("socks: session not found")
+
+	// ErrAuthFailed is returned when the user name or the password is rejected.
+	ErrAuthFailed = errors.New("socks: authentication failed")
+
+	// ErrUnsupportedVersion is returned when the client does not use SOCKS5.
+	ErrUnsupportedVersion = errors.New("socks: unsupported version")
+
+	errAddrTooLong = errors.New("socks: address too long")
+)
+
+type (
+	// Option is used to configure the SOCKS5 server.
+	Option func(*Server)
+
+	// SessionSelector returns the session of the CONNECT request.
+	// The user is the SOCKS5 user name, it is empty without the authentication.
+	SessionSelector func(w wirenet.Wire, user string) (wirenet.Session, error)
+
+	// Authenticator is used to validate the SOCKS5 user name and password.
+	Authenticator func(user, password string) error
+)
+
+// WithIdentification selects the session of the client with the identification for all requests.
+func WithIdentification(id wirenet.Identification) Option {
+	return func(s *Server) {
+		s.selectSession = func(w wirenet.Wire, _ string) (wirenet.Session, error) {
+			return findSession(w, id)
+		}
+	}
+}
+
+// WithSessionSelector sets the session selection.
+// The default is the session of the client with the identification equal to the user name,
+// or the only session if the user name is empty.
+func WithSessionSelector(fn SessionSelector) Option {
+	return func(s *Server) {
+		s.selectSession = fn
+	}
+}
+
+// WithAuthenticator requires the user name and password authentication.
+func WithAuthenticator(fn Authenticator) Option {
+	return func(s *Server) {
+		s.authenticate = fn
+	}
+}
+
+// WithErrorHandler sets the handler of the errors of the connections.
+func WithErrorHandler(fn func(error)) Option {
+	return func(s *Server) {
+		s.errorHandler = fn
+	}
+}
+
+// Server is the local SOCKS5 server, the connections are egressed through the remote session.
+type Server struct {
+	wire          wirenet.Wire
+	selectSession SessionSelector
+	authenticate  Authenticator
+	errorHandler  func(error)
+}
+
+// NewServer returns the SOCKS5 server of the wire.
+func NewServer(w wirenet.Wire, opts ...Option) *Server {
+	s := &Server{
+		wire:          w,
+		selectSession: DefaultSessionSelector,
+		errorHandler:  func(error) {},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// DefaultSessionSelector returns the session of the client with the identification equal to the user name,
+// or the only session if the user name is empty.
+func DefaultSessionSelector(w wirenet.Wire, user string) (wirenet.Session, error) {
+	if len(user) > 0 {
+		return findSession(w, wirenet.Identification(user))
+	}
+	sessions := w.Sessions()
+	if len(sessions) != 1 {
+		return nil, ErrNoSession
+	}
+	for _, sess := range sessions {
+		return sess, nil
+	}
+	return nil, ErrNoSession
+}
+
+func findSession(w wirenet.Wire, id wirenet.Identification) (wirenet.Session, error) {
+	for _, sess := range w.Sessions() {
+		if string(sess.Identification()) == string(id) {
+			return sess, nil
+		}
+	}
+	return nil, ErrNoSession
+}
+
+// Serve accepts the SOCKS5 connections until the listener is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := s.serveConn(conn); err != nil {
+				s.errorHandler(err)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) error {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+
+	_ = conn.SetDeadline(time.Now().Add(DefaultDialTimeout))
+	user, err := s.handshake(rd, conn)
+	if err != nil {
+		return err
+	}
+	addr, rep, err := readRequest(rd)
+	if err != nil {
+		return err
+	}
+	if rep != repSucceeded {
+		return writeReply(conn, rep)
+	}
+
+	stream, rep, err := s.open(user, addr)
+	if err != nil {
+		_ = writeReply(conn, rep)
+		return err
+	}
+	if err := writeReply(conn, rep); err != nil || rep != repSucceeded {
+		_ = stream.Close()
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	// the data sent by the client before the reply is buffered
+	if n := rd.Buffered(); n > 0 {
+		buf, _ := rd.Peek(n)
+		if _, err := stream.Write(buf); err != nil {
+			_ = stream.Close()
+			return err
+		}
+	}
+	// the stream is closed by the relay
+	wirenet.Relay(conn, stream)
+	return nil
+}
+
+// open opens the stream of the destination on the selected session and waits for the reply of the egress.
+func (s *Server) open(user, addr string) (net.Conn, byte, error) {
+	sess, err := s.selectSession(s.wire, user)
+	if err != nil {
+		return nil, repGeneralFailure, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDialTimeout)
+	defer cancel()
+	stream, err := wirenet.DialStream(sess, StreamName)(ctx, addr)
+	if err != nil {
+		return nil, repGeneralFailure, err
+	}
+	_ = stream.SetReadDeadline(time.Now().Add(2 * DefaultDialTimeout))
+	rep := make([]byte, 1)
+	if err := writeAddr(stream, addr); err != nil {
+		_ = stream.Close()
+		return nil, repGeneralFailure, err
+	}
+	if _, err := io.ReadFull(stream, rep); err != nil {
+		_ = stream.Close()
+		return nil, repGeneralFailure, err
+	}
+	_ = stream.SetReadDeadline(time.Time{})
+	return stream, rep[0], nil
+}
+
+// handshake negotiates the authentication method and returns the user name.
+func (s *Server) handshake(rd *bufio.Reader, w io.Writer) (string, error) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(rd, hdr); err != nil {
+		return "", err
+	}
+	if hdr[0] != socks5Version {
+		return "", ErrUnsupportedVersion
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(rd, methods); err != nil {
+		return "", err
+	}
+
+	// the user name is used to select the session, so the password method is preferred
+	method := byte(authNoAcceptable)
+	for _, m := range methods {
+		if m == authPassword {
+			method = authPassword
+			break
+		}
+		if m == authNone && s.authenticate == nil {
+			method = authNone
+		}
+	}
+	if _, err := w.Write([]byte{socks5Version, method}); err != nil {
+		return "", err
+	}
+	switch method {
+	case authNone:
+		return "", nil
+	case authPassword:
+		return s.passwordAuth(rd, w)
+	}
+	return "", ErrAuthFailed
+}
+
+// passwordAuth reads the user name and password, see RFC 1929.
+func (s *Server) passwordAuth(rd *bufio.Reader, w io.Writer) (string, error) {
+	ver, err := rd.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if ver != authVersion {
+		return "", ErrUnsupportedVersion
+	}
+	user, err := readString(rd)
+	if err != nil {
+		return "", err
+	}
+	password, err := readString(rd)
+	if err != nil {
+		return "", err
+	}
+	if s.authenticate != nil {
+		if err := s.authenticate(user, password); err != nil {
+			_, _ = w.Write([]byte{authVersion, 0x01})
+			return "", ErrAuthFailed
+		}
+	}
+	if _, err := w.Write([]byte{authVersion, 0x00}); err != nil {
+		return "", err
+	}
+	return user, nil
+}
+
+// readRequest reads the CONNECT request and returns the destination address.
+func readRequest(rd *bufio.Reader) (string, byte, error) {
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(rd, hdr); err != nil {
+		return "", 0, err
+	}
+	if hdr[0] != socks5Version {
+		return "", 0, ErrUnsupportedVersion
+	}
+
+	var host string
+	switch hdr[3] {
+	case atypIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(rd, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case atypIPv6:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(rd, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case atypDomain:
+		name, err := readString(rd)
+		if err != nil {
+			return "", 0, err
+		}
+		host = name
+	default:
+		return "", repAddressNotSupported, nil
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(rd, port); err != nil {
+		return "", 0, err
+	}
+	if hdr[1] != cmdConnect {
+		return "", repCommandNotSupported, nil
+	}
+	return net.JoinHostPort(host, portString(binary.BigEndian.Uint16(port))), repSucceeded, nil
+}
+
+func readString(rd *bufio.Reader) (string, error) {
+	size, err := rd.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(rd, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// writeReply writes the reply with the zero bound address.
+func writeReply(w io.Writer, rep byte) error {
+	_, err := w.Write([]byte{socks5Version, rep, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
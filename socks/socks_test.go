@@ -0,0 +1,221 @@
+package socks
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mediabuyerbot/go-wirenet"
+	"github.com/stretchr/testify/assert"
+)
+
+// upperServer returns the address of the TCP server which upper-cases the lines until the connection is closed.
+func upperServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write([]byte(strings.ToUpper(line)))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), func() {
+		_ = listener.Close()
+	}
+}
+
+// connectEgress connects the client with the identification which egresses with the rules,
+// and returns the address of the SOCKS5 server on the server side.
+func connectEgress(t *testing.T, rules Rules, opts ...Option) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	initSrv := make(chan struct{})
+	initSess := make(chan struct{})
+
+	// server side
+	server, err := wirenet.Mount("",
+		wirenet.WithListener(listener),
+		wirenet.WithConnectHook(func(io.Closer) {
+			close(initSrv)
+		}),
+		wirenet.WithSessionOpenHook(func(wirenet.Session) {
+			close(initSess)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := wirenet.Join(listener.Addr().String(),
+		wirenet.WithIdentification(wirenet.Identification("macbook"), wirenet.Token("token")))
+	assert.Nil(t, err)
+	egress := ServeEgress(client, rules)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initSess
+
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		_ = NewServer(server, opts...).Serve(proxy)
+	}()
+	return proxy.Addr().String(), func() {
+		_ = proxy.Close()
+		assert.Nil(t, egress.Close())
+		assert.Nil(t, client.Close())
+		assert.Nil(t, server.Close())
+	}
+}
+
+// connect sends the CONNECT request to the SOCKS5 server and returns the reply code.
+func connect(t *testing.T, proxyAddr, user, targetAddr string) (net.Conn, byte) {
+	conn, err := net.Dial("tcp", proxyAddr)
+	assert.Nil(t, err)
+	rd := bufio.NewReader(conn)
+	reply := make([]byte, 2)
+
+	if len(user) > 0 {
+		_, err = conn.Write([]byte{socks5Version, 1, authPassword})
+		assert.Nil(t, err)
+		_, err = io.ReadFull(rd, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{socks5Version, authPassword}, reply)
+		auth := append([]byte{authVersion, byte(len(user))}, user...)
+		auth = append(auth, 4, 'p', 'a', 's', 's')
+		_, err = conn.Write(auth)
+		assert.Nil(t, err)
+		_, err = io.ReadFull(rd, reply)
+		assert.Nil(t, err)
+		if reply[1] != 0x00 {
+			_ = conn.Close()
+			return nil, repNotAllowed
+		}
+	} else {
+		_, err = conn.Write([]byte{socks5Version, 1, authNone})
+		assert.Nil(t, err)
+		_, err = io.ReadFull(rd, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{socks5Version, authNone}, reply)
+	}
+
+	host, port, err := net.SplitHostPort(targetAddr)
+	assert.Nil(t, err)
+	p, err := strconv.Atoi(port)
+	assert.Nil(t, err)
+	req := append([]byte{socks5Version, cmdConnect, 0x00, atypDomain, byte(len(host))}, host...)
+	req = append(req, 0, 0)
+	binary.BigEndian.PutUint16(req[len(req)-2:], uint16(p))
+	_, err = conn.Write(req)
+	assert.Nil(t, err)
+
+	resp := make([]byte, 10)
+	_, err = io.ReadFull(rd, resp)
+	assert.Nil(t, err)
+	return conn, resp[1]
+}
+
+func assertUpper(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for _, msg := range []string{"ping\n", "pong\n"} {
+		_, err := conn.Write([]byte(msg))
+		assert.Nil(t, err)
+		line, err := rd.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, strings.ToUpper(msg), line)
+	}
+	assert.Nil(t, conn.(*net.TCPConn).CloseWrite())
+	rest, err := ioutil.ReadAll(rd)
+	assert.Nil(t, err)
+	assert.Empty(t, rest)
+}
+
+func TestServer_Connect(t *testing.T) {
+	target, closeTarget := upperServer(t)
+	defer closeTarget()
+	_, port, _ := net.SplitHostPort(target)
+
+	proxyAddr, closeWires := connectEgress(t, Rules{Allow: []string{"localhost"}})
+	defer closeWires()
+
+	// the only session
+	conn, rep := connect(t, proxyAddr, "", "localhost:"+port)
+	assert.Equal(t, byte(repSucceeded), rep)
+	assertUpper(t, conn)
+
+	// the session of the user name
+	conn, rep = connect(t, proxyAddr, "macbook", "localhost:"+port)
+	assert.Equal(t, byte(repSucceeded), rep)
+	assertUpper(t, conn)
+
+	conn, rep = connect(t, proxyAddr, "unknown", "localhost:"+port)
+	assert.Equal(t, byte(repGeneralFailure), rep)
+	assert.Nil(t, conn.Close())
+
+	// not in the allow list
+	conn, rep = connect(t, proxyAddr, "", target)
+	assert.Equal(t, byte(repNotAllowed), rep)
+	assert.Nil(t, conn.Close())
+}
+
+func TestServer_ConnectDenied(t *testing.T) {
+	target, closeTarget := upperServer(t)
+	defer closeTarget()
+
+	proxyAddr, closeWires := connectEgress(t, Rules{Deny: []string{"127.0.0.0/8"}},
+		WithIdentification(wirenet.Identification("macbook")))
+	defer closeWires()
+
+	conn, rep := connect(t, proxyAddr, "", target)
+	assert.Equal(t, byte(repNotAllowed), rep)
+	assert.Nil(t, conn.Close())
+}
+
+func TestServer_Authenticator(t *testing.T) {
+	target, closeTarget := upperServer(t)
+	defer closeTarget()
+
+	proxyAddr, closeWires := connectEgress(t, Rules{}, WithAuthenticator(func(user, password string) error {
+		if password != "pass" {
+			return ErrAuthFailed
+		}
+		return nil
+	}))
+	defer closeWires()
+
+	conn, rep := connect(t, proxyAddr, "macbook", target)
+	assert.Equal(t, byte(repSucceeded), rep)
+	assertUpper(t, conn)
+
+	// the authentication is required
+	conn, err := net.Dial("tcp", proxyAddr)
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{socks5Version, 1, authNone})
+	assert.Nil(t, err)
+	reply := make([]byte, 2)
+	_, err = io.ReadFull(conn, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{socks5Version, authNoAcceptable}, reply)
+}
@@ -0,0 +1,81 @@
+package wirenet
+
+import "time"
+
+// State represents the connection state of the wire.
+type State int
+
+const (
+	// StateIdle is the state of the wire before Connect().
+	StateIdle State = iota
+
+	// StateConnecting is the state of the first connection attempt on the client side
+	// or the listening start on the server side.
+	StateConnecting
+
+	// StateConnected is the state of the established connection on the client side
+	// or the listening on the server side.
+	StateConnected
+
+	// StateReconnecting is the state of the waiting for the next connection attempt
+	// or the next connection attempt on the client side.
+	StateReconnecting
+
+	// StateClosed is the state of the closed wire or the wire that gave up after the last attempt.
+	StateClosed
+)
+
+func (s State) String() (state string) {
+	switch s {
+	case StateIdle:
+		state = "idle"
+	case StateConnecting:
+		state = "connecting"
+	case StateConnected:
+		state = "connected"
+	case StateReconnecting:
+		state = "reconnecting"
+	case StateClosed:
+		state = "closed"
+	default:
+		state = "unknown"
+	}
+	return state
+}
+
+// StateInfo describes the connection state of the wire.
+type StateInfo struct {
+	// Attempt is the number of the connection attempt. Used only on the client side.
+	Attempt int
+
+	// Endpoint is the address of the connected server on the client side or the listening address on the server side.
+	Endpoint string
+
+	// Err is the last dial error on the client side or the listening error on the server side.
+	// In StateClosed it is the error returned by Connect().
+	Err error
+
+	// NextRetry is the time of the next connection attempt computed by RetryPolicy.
+	// Used only on the client side in StateReconnecting.
+	NextRetry time.Time
+}
+
+// StateHook is used when the connection state of the wire changes.
+// The hook is called synchronously, so it must not block.
+type StateHook func(State, StateInfo)
+
+func (w *wire) setState(state State, info StateInfo) {
+	w.mu.Lock()
+	w.state = state
+	w.stateInfo = info
+	hook := w.stateHook
+	w.mu.Unlock()
+
+	hook(state, info)
+}
+
+func (w *wire) State() (State, StateInfo) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state, w.stateInfo
+}
@@ -0,0 +1,111 @@
+package wirenet
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "idle", StateIdle.String())
+	assert.Equal(t, "connecting", StateConnecting.String())
+	assert.Equal(t, "connected", StateConnected.String())
+	assert.Equal(t, "reconnecting", StateReconnecting.String())
+	assert.Equal(t, "closed", StateClosed.String())
+	assert.Equal(t, "unknown", State(999).String())
+}
+
+func TestWire_StateReconnect(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		states []State
+		infos  []StateInfo
+	)
+	client, err := Join(genAddr(t),
+		WithRetryMax(2),
+		WithRetryWait(100*time.Millisecond, 100*time.Millisecond),
+		WithStateHook(func(state State, info StateInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, state)
+			infos = append(infos, info)
+		}),
+	)
+	assert.Nil(t, err)
+	state, _ := client.State()
+	assert.Equal(t, StateIdle, state)
+
+	start := time.Now()
+	assert.Error(t, client.Connect())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []State{
+		StateConnecting,
+		StateReconnecting,
+		StateReconnecting,
+		StateReconnecting,
+		StateClosed,
+	}, states)
+	assert.Equal(t, 1, infos[0].Attempt)
+	assert.Equal(t, 1, infos[1].Attempt)
+	assert.Error(t, infos[1].Err)
+	assert.True(t, infos[1].NextRetry.After(start))
+	assert.Equal(t, 2, infos[2].Attempt)
+	assert.Nil(t, infos[2].Err)
+	assert.Equal(t, 2, infos[3].Attempt)
+	assert.Error(t, infos[3].Err)
+	assert.Equal(t, 2, infos[4].Attempt)
+	assert.Error(t, infos[4].Err)
+
+	state, info := client.State()
+	assert.Equal(t, StateClosed, state)
+	assert.Equal(t, infos[4], info)
+}
+
+func TestWire_StateConnected(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// server side
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	state, info := server.State()
+	assert.Equal(t, StateConnected, state)
+	assert.Equal(t, server.Endpoint(), info.Endpoint)
+
+	// client side
+	client, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		close(initCli)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	state, info = client.State()
+	assert.Equal(t, StateConnected, state)
+	assert.Equal(t, addr, info.Endpoint)
+	assert.Equal(t, 1, info.Attempt)
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+	<-done
+
+	state, _ = server.State()
+	assert.Equal(t, StateClosed, state)
+}
@@ -2,18 +2,23 @@ package wirenet
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"io"
 	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/yamux"
 )
 
 const (
-	BufSize = 1 << 10
-	eof     = uint32(0)
+	BufSize  = 1 << 10
+	eof      = uint32(0)
+	errChunk = ^uint32(0)
 )
 
 // Stream is a named stream for streaming data.
@@ -39,6 +44,32 @@ type Stream interface {
 	// Writer returns a writer.
 	Writer() io.WriteCloser
 
+	// Metadata returns the key/value headers sent by OpenStreamContext().
+	Metadata() Metadata
+
+	// CloseWithError closes the stream and sends the error to the peer.
+	// The reader of the peer returns *RemoteError with the code and the message.
+	CloseWithError(code ErrorCode, msg string) error
+
+	// Conn returns the net.Conn of the stream for the libraries that work with net.Conn (TLS, HTTP, etc.).
+	// The chunks are hidden, the peer can use Reader() and Writer() or Conn() of the stream.
+	// The returned connection also implements CloseWrite() error to shut down the writing side.
+	// The connection can be idle for a long time, so the read timeout set by WithReadWriteTimeouts() is disabled,
+	// the deadlines of the connection are used instead.
+	Conn() net.Conn
+
+	// SetDeadline sets the read and write deadlines of the stream.
+	// The read deadline replaces the read timeout set by WithReadWriteTimeouts(), a zero value means no deadline.
+	SetDeadline(t time.Time) error
+
+	// SetReadDeadline sets the read deadline of the stream.
+	// The deadline replaces the read timeout set by WithReadWriteTimeouts(), a zero value means no deadline,
+	// so the long-lived stream disables the read timeout with SetReadDeadline(time.Time{}).
+	SetReadDeadline(t time.Time) error
+
+	// SetWriteDeadline sets the write deadline of the stream.
+	SetWriteDeadline(t time.Time) error
+
 	io.Closer
 	io.ReaderFrom
 	io.WriterTo
@@ -48,19 +79,24 @@ type stream struct {
 	id     uuid.UUID
 	sess   *session
 	name   string
-	conn   *yamux.Stream
+	conn   *streamConn
 	closed bool
+	cancel context.CancelFunc
+	nc     *netConn
+	md     Metadata
+	rerr   atomic.Value
 	buf    []byte
 	hdr    []byte
 	mu     sync.RWMutex
 }
 
-func openStream(sess *session, name string, conn *yamux.Stream) Stream {
+func openStream(sess *session, name string, md Metadata, conn *yamux.Stream) *stream {
 	stream := &stream{
 		id:   uuid.New(),
 		sess: sess,
 		name: name,
-		conn: conn,
+		md:   md,
+		conn: newStreamConn(conn, sess.w.readTimeout),
 		buf:  make([]byte, BufSize),
 		hdr:  make([]byte, hdrLen),
 	}
@@ -70,6 +106,17 @@ func openStream(sess *session, name string, conn *yamux.Stream) Stream {
 	return stream
 }
 
+// context returns the handler context.
+// The context is canceled when the stream is closed locally or by the peer, or the session is closed.
+func (s *stream) context(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	go s.conn.watchPeer(ctx, cancel)
+	return ctx
+}
+
 func (s *stream) IsClosed() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -94,6 +141,10 @@ func (s *stream) Name() string {
 	return s.name
 }
 
+func (s *stream) Metadata() Metadata {
+	return s.md
+}
+
 func (s *stream) writeEOF() error {
 	if err := binary.Write(s.conn, binary.LittleEndian, eof); err != nil {
 		return err
@@ -109,6 +160,9 @@ func (s *stream) writeHdr(size int) error {
 }
 
 func (s *stream) readHeader() (size int, err error) {
+	if err := s.remoteError(); err != nil {
+		return 0, err
+	}
 	n, err := s.conn.Read(s.hdr)
 	if err != nil {
 		return size, err
@@ -117,13 +171,51 @@ func (s *stream) readHeader() (size int, err error) {
 		return size, io.ErrShortBuffer
 	}
 	bs := binary.LittleEndian.Uint32(s.hdr)
-	if bs == eof {
+	switch bs {
+	case eof:
 		return 0, io.EOF
+	case errChunk:
+		return 0, s.readRemoteError()
 	}
 	size = int(bs)
 	return size, nil
 }
 
+func (s *stream) writeError(code ErrorCode, msg string) error {
+	if err := binary.Write(s.conn, binary.LittleEndian, errChunk); err != nil {
+		return err
+	}
+	return newEncoder(s.conn).Encode(errFrameTyp, strconv.FormatUint(uint64(code), 10), []byte(msg))
+}
+
+// readRemoteError reads the error frame sent by CloseWithError() of the peer.
+func (s *stream) readRemoteError() error {
+	frm, err := newDecoder(s.conn).Decode()
+	if err != nil {
+		return err
+	}
+	if !frm.IsErrFrame() {
+		return io.ErrUnexpectedEOF
+	}
+	code, err := strconv.ParseUint(frm.Command(), 10, 32)
+	if err != nil {
+		return err
+	}
+	remoteErr := &RemoteError{
+		Code:    ErrorCode(code),
+		Message: string(frm.Payload()),
+	}
+	s.rerr.Store(remoteErr)
+	return remoteErr
+}
+
+func (s *stream) remoteError() error {
+	if err, ok := s.rerr.Load().(*RemoteError); ok {
+		return err
+	}
+	return nil
+}
+
 func (s *stream) read(offset int) (n int, err error) {
 	for n < offset {
 		rn, re := s.conn.Read(s.buf[n:offset])
@@ -165,7 +257,10 @@ func (s *stream) WriteTo(w io.Writer) (n int64, err error) {
 		}
 
 		size, erh := s.readHeader()
-		if erh == io.EOF {
+		if erh != nil {
+			if erh != io.EOF {
+				err = erh
+			}
 			break
 		}
 
@@ -252,11 +347,54 @@ func (s *stream) Writer() io.WriteCloser {
 	}
 }
 
+func (s *stream) Conn() net.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nc == nil {
+		s.conn.disableReadTimeout()
+		s.nc = newNetConn(s)
+	}
+	return s.nc
+}
+
+func (s *stream) SetDeadline(t time.Time) error {
+	return s.conn.SetDeadline(t)
+}
+
+func (s *stream) SetReadDeadline(t time.Time) error {
+	return s.conn.SetReadDeadline(t)
+}
+
+func (s *stream) SetWriteDeadline(t time.Time) error {
+	return s.conn.SetWriteDeadline(t)
+}
+
+func (s *stream) CloseWithError(code ErrorCode, msg string) error {
+	if s.IsClosed() {
+		return ErrStreamClosed
+	}
+	writeErr := s.writeError(code, msg)
+	if err := s.Close(); err != nil {
+		return err
+	}
+	return writeErr
+}
+
 func (s *stream) Close() error {
 	s.mu.Lock()
+	// the stream is closed once, so the active streams of the session are counted correctly
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
 	s.closed = true
+	cancel := s.cancel
 	s.mu.Unlock()
 
+	if cancel != nil {
+		cancel()
+	}
+
 	s.sess.unregisterStream(s)
 	_ = s.conn.Close()
 	return nil
@@ -351,6 +489,9 @@ func (r *reader) Read(p []byte) (n int, err error) {
 	total := r.buf.Len()
 	for total < len(p) && !r.eof {
 		if fer := r.fill(); fer != nil {
+			if isStreamError(fer) && r.buf.Len() == 0 {
+				return 0, fer
+			}
 			break
 		}
 		total = r.buf.Len()
@@ -362,6 +503,127 @@ func (r *reader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// streamConn is the yamux stream with the idle read timeout.
+// The read timeout is used until the read deadline is set explicitly.
+type streamConn struct {
+	*yamux.Stream
+	readTimeout  time.Duration
+	idleDeadline time.Time
+	activity     chan struct{}
+	mu           sync.Mutex
+}
+
+func newStreamConn(conn *yamux.Stream, readTimeout time.Duration) *streamConn {
+	return &streamConn{
+		Stream:      conn,
+		readTimeout: readTimeout,
+		activity:    make(chan struct{}, 1),
+	}
+}
+
+func (c *streamConn) notify() {
+	select {
+	case c.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (c *streamConn) Read(p []byte) (n int, err error) {
+	c.mu.Lock()
+	if c.readTimeout > 0 {
+		c.idleDeadline = time.Now().Add(c.readTimeout)
+		_ = c.Stream.SetReadDeadline(c.idleDeadline)
+	}
+	c.mu.Unlock()
+
+	n, err = c.Stream.Read(p)
+	c.notify()
+	return n, streamErr(err)
+}
+
+func (c *streamConn) Write(p []byte) (n int, err error) {
+	n, err = c.Stream.Write(p)
+	return n, streamErr(err)
+}
+
+func (c *streamConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline disables the idle read timeout, the zero value means no deadline like net.Conn.
+func (c *streamConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readTimeout = 0
+	c.idleDeadline = time.Time{}
+	c.mu.Unlock()
+	defer c.notify()
+	return c.Stream.SetReadDeadline(t)
+}
+
+// disableReadTimeout disables the idle read timeout, the deadline set explicitly is kept.
+func (c *streamConn) disableReadTimeout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readTimeout = 0
+	if !c.idleDeadline.IsZero() {
+		c.idleDeadline = time.Time{}
+		_ = c.Stream.SetReadDeadline(time.Time{})
+	}
+}
+
+// clearIdleDeadline clears the expired idle read timeout, so the peer close is noticed while the handler does not read.
+// The next read sets the timeout again. The deadline set explicitly is not cleared.
+func (c *streamConn) clearIdleDeadline() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idleDeadline.IsZero() || time.Now().Before(c.idleDeadline) {
+		return false
+	}
+	c.idleDeadline = time.Time{}
+	_ = c.Stream.SetReadDeadline(time.Time{})
+	return true
+}
+
+// watchPeer cancels the context when the peer closes or resets the stream.
+// The received data is not consumed, so the peer close is noticed after the data is read.
+func (c *streamConn) watchPeer(ctx context.Context, cancel context.CancelFunc) {
+	for {
+		// returns immediately if the data is received, otherwise waits for the data, the close or the deadline
+		_, err := c.Stream.Read(nil)
+		if err != nil && err != yamux.ErrTimeout {
+			cancel()
+			return
+		}
+		if err == yamux.ErrTimeout && c.clearIdleDeadline() {
+			continue
+		}
+		select {
+		case <-c.activity:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isStreamError returns a true flag if the error is returned by the reader instead of io.EOF.
+func isStreamError(err error) bool {
+	if err == ErrDeadlineExceeded {
+		return true
+	}
+	_, ok := err.(*RemoteError)
+	return ok
+}
+
+func streamErr(err error) error {
+	if err == yamux.ErrTimeout {
+		return ErrDeadlineExceeded
+	}
+	return err
+}
+
 func pipe(src net.Conn, dst net.Conn) (err error) {
 	b := make([]byte, BufSize)
 	for {
@@ -2,8 +2,12 @@ package wirenet
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -26,7 +30,7 @@ func makeReadStream(addr string, t *testing.T) *stream {
 	assert.Nil(t, err)
 
 	return &stream{
-		conn: sconn,
+		conn: newStreamConn(sconn, 0),
 		buf:  make([]byte, BufSize),
 		hdr:  make([]byte, hdrLen),
 		sess: new(session),
@@ -45,7 +49,7 @@ func makeWriteStream(addr string, t *testing.T) *stream {
 	assert.Nil(t, err)
 
 	return &stream{
-		conn: sconn,
+		conn: newStreamConn(sconn, 0),
 		buf:  make([]byte, BufSize),
 		hdr:  make([]byte, hdrLen),
 		sess: new(session),
@@ -208,3 +212,220 @@ func TestStream_ReadWriteTo(t *testing.T) {
 
 	<-done
 }
+
+func TestStream_Deadlines(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+	handled := make(chan struct{})
+
+	// server side
+	server, err := Mount(addr,
+		WithReadWriteTimeouts(200*time.Millisecond, time.Second),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	server.Stream("deadline", func(ctx context.Context, s Stream) {
+		defer close(handled)
+		// idle read timeout
+		start := time.Now()
+		_, err := s.Reader().Read(make([]byte, 1))
+		assert.Equal(t, ErrDeadlineExceeded, err)
+		assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+		// explicit deadline
+		assert.Nil(t, s.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+		_, err = s.Reader().Read(make([]byte, 1))
+		assert.Equal(t, ErrDeadlineExceeded, err)
+		netErr, ok := err.(net.Error)
+		assert.True(t, ok)
+		assert.True(t, netErr.Timeout())
+
+		// no deadline, the read waits longer than the read timeout
+		assert.Nil(t, s.SetReadDeadline(time.Time{}))
+		buf := bytes.NewBuffer(nil)
+		_, err = s.WriteTo(buf)
+		assert.Nil(t, err)
+		assert.Equal(t, "data", buf.String())
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr,
+		WithSessionOpenHook(func(s Session) {
+			initCli <- s
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("deadline")
+	assert.Nil(t, err)
+	time.Sleep(600 * time.Millisecond)
+	_, err = stream.ReadFrom(strings.NewReader("data"))
+	assert.Nil(t, err)
+	<-handled
+	assert.Nil(t, stream.Close())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestStream_ContextCanceledByPeer(t *testing.T) {
+	testContextCanceledByPeer(t, 0)
+}
+
+func TestStream_ContextCanceledByPeerAfterReadTimeout(t *testing.T) {
+	// the handler only waits after the request, so the idle read timeout is reached before the peer closes
+	testContextCanceledByPeer(t, 600*time.Millisecond, WithReadWriteTimeouts(200*time.Millisecond, time.Hour))
+}
+
+func testContextCanceledByPeer(t *testing.T, closeAfter time.Duration, opts ...Option) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+	received := make(chan struct{})
+	canceled := make(chan error)
+
+	// server side
+	opts = append(opts, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	server, err := Mount(addr, opts...)
+	assert.Nil(t, err)
+	server.Stream("cancel", func(ctx context.Context, s Stream) {
+		_, err := s.WriteTo(ioutil.Discard)
+		assert.Nil(t, err)
+		close(received)
+		select {
+		case <-ctx.Done():
+			canceled <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			canceled <- nil
+		}
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr,
+		WithSessionOpenHook(func(s Session) {
+			initCli <- s
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("cancel")
+	assert.Nil(t, err)
+	_, err = stream.ReadFrom(bytes.NewReader([]byte("request")))
+	assert.Nil(t, err)
+	<-received
+	time.Sleep(closeAfter)
+	assert.Nil(t, stream.Close())
+	assert.Equal(t, context.Canceled, <-canceled)
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestStream_CloseTwice(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"wait": func(ctx context.Context, s Stream) {
+			<-ctx.Done()
+		},
+	})
+	defer closeWires()
+
+	s1, err := sess.OpenStream("wait")
+	assert.Nil(t, err)
+	s2, err := sess.OpenStream("wait")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, sess.(*session).activeStreamCounter())
+
+	// the second close does not unregister the other stream
+	assert.Nil(t, s1.Close())
+	assert.Nil(t, s1.Close())
+	assert.Nil(t, s1.Conn().Close())
+	assert.Equal(t, 1, sess.(*session).activeStreamCounter())
+
+	assert.Nil(t, s2.Close())
+	assert.Equal(t, 0, sess.(*session).activeStreamCounter())
+}
+
+func TestStream_CloseWithError(t *testing.T) {
+	sess, closeWires := connectStreams(t, map[string]Handler{
+		"file": func(ctx context.Context, stream Stream) {
+			_, err := stream.ReadFrom(bytes.NewReader([]byte("partial")))
+			assert.Nil(t, err)
+			assert.Nil(t, stream.CloseWithError(404, "file not found"))
+			assert.Equal(t, ErrStreamClosed, stream.CloseWithError(404, "file not found"))
+		},
+		"chunk": func(ctx context.Context, stream Stream) {
+			_, err := stream.Writer().Write([]byte("partial"))
+			assert.Nil(t, err)
+			assert.Nil(t, stream.CloseWithError(400, "bad request"))
+		},
+		"panic": func(ctx context.Context, stream Stream) {
+			panic("boom")
+		},
+	})
+	defer closeWires()
+
+	// the error is read after the end of the data
+	stream, err := sess.OpenStream("file")
+	assert.Nil(t, err)
+	buf := bytes.NewBuffer(nil)
+	n, err := stream.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), n)
+	assert.Equal(t, "partial", buf.String())
+	_, err = stream.Reader().Read(make([]byte, 1))
+	assert.Equal(t, &RemoteError{Code: 404, Message: "file not found"}, err)
+	_, err = stream.WriteTo(buf)
+	assert.Equal(t, &RemoteError{Code: 404, Message: "file not found"}, err)
+	assert.Nil(t, stream.Close())
+
+	// the data is read before the error
+	stream, err = sess.OpenStream("chunk")
+	assert.Nil(t, err)
+	buf.Reset()
+	n, err = stream.WriteTo(buf)
+	assert.Equal(t, &RemoteError{Code: 400, Message: "bad request"}, err)
+	assert.Equal(t, int64(7), n)
+	assert.Equal(t, "partial", buf.String())
+	assert.Nil(t, stream.Close())
+
+	// reader
+	stream, err = sess.OpenStream("chunk")
+	assert.Nil(t, err)
+	p, err := ioutil.ReadAll(stream.Reader())
+	assert.Equal(t, &RemoteError{Code: 400, Message: "bad request"}, err)
+	assert.Equal(t, "partial", string(p))
+	assert.Nil(t, stream.Close())
+
+	// net.Conn
+	stream, err = sess.OpenStream("chunk")
+	assert.Nil(t, err)
+	p, err = ioutil.ReadAll(stream.Conn())
+	assert.Equal(t, &RemoteError{Code: 400, Message: "bad request"}, err)
+	assert.Equal(t, "partial", string(p))
+	assert.Nil(t, stream.Close())
+
+	// panic
+	stream, err = sess.OpenStream("panic")
+	assert.Nil(t, err)
+	_, err = stream.WriteTo(ioutil.Discard)
+	assert.Equal(t, &RemoteError{Code: ErrorCodeInternal, Message: "panic: boom"}, err)
+	assert.Nil(t, stream.Close())
+}
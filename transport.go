@@ -0,0 +1,239 @@
+package wirenet
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type (
+	// Dialer is used to establish a client side connection with the given addr.
+	// See WithDialer().
+	Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+	// Transport is used to listen and dial connections for the wire.
+	// The default transport supports TCP addresses, Unix domain sockets
+	// like unix:///path/to.sock or unix:@abstract and WebSocket URLs like ws://host:port/path.
+	// If TLS is enabled with WithTLS(), the connections are wrapped after Listen() and Dial(),
+	// except WebSocket URLs where TLS is negotiated by the wss scheme.
+	Transport interface {
+
+		// Listen announces on the given addr. Used only on the server side.
+		Listen(addr string) (net.Listener, error)
+
+		// Dial connects to the given addr. Used only on the client side.
+		Dial(ctx context.Context, addr string) (net.Conn, error)
+	}
+)
+
+const unixScheme = "unix:"
+
+type netTransport struct {
+	dialer     net.Dialer
+	socketMode os.FileMode
+	tlsConfig  *tls.Config
+	proxy      proxyFunc
+}
+
+func (t *netTransport) Listen(addr string) (net.Listener, error) {
+	if isWebSocketAddr(addr) {
+		return listenWebSocket(addr, t.tlsConfig)
+	}
+	path, ok := unixSocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	if t.socketMode != 0 && !isAbstractSocket(path) {
+		return listenUnixMode(path, t.socketMode)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(true)
+	return listener, nil
+}
+
+// listenUnixMode binds the socket in the private directory next to the path, sets the permissions
+// and links it to the path, so the socket is never reachable with the permissions of the umask.
+// The temporary path is a few characters longer than the path, see the length limit of the socket path.
+func listenUnixMode(path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".w")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "s")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	// unlike rename, the link fails if the path is already used by the other listener
+	if err := os.Link(tmpPath, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &unixListener{
+		UnixListener: listener,
+		addr:         &net.UnixAddr{Name: path, Net: "unix"},
+	}, nil
+}
+
+// unixListener is the socket bound to the temporary path and linked to the address.
+type unixListener struct {
+	*net.UnixListener
+	addr *net.UnixAddr
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if rmErr := os.Remove(l.addr.Name); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func (t *netTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	if isWebSocketAddr(addr) {
+		return dialWebSocket(ctx, addr, t.dialTCP, t.tlsConfig)
+	}
+	if path, ok := unixSocketPath(addr); ok {
+		return t.dialer.DialContext(ctx, "unix", path)
+	}
+	return t.dialTCP(ctx, addr)
+}
+
+func (t *netTransport) dialTCP(ctx context.Context, addr string) (net.Conn, error) {
+	if t.proxy != nil {
+		proxyURL, err := t.proxy(addr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			return dialProxy(ctx, &t.dialer, proxyURL, addr)
+		}
+	}
+	return t.dialer.DialContext(ctx, "tcp", addr)
+}
+
+// unixSocketPath returns the socket path from addresses like unix:///path/to.sock or unix:@abstract.
+func unixSocketPath(addr string) (path string, ok bool) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		return "", false
+	}
+	path = strings.TrimPrefix(addr, unixScheme)
+	path = strings.TrimPrefix(path, "//")
+	return path, len(path) > 0
+}
+
+func isAbstractSocket(path string) bool {
+	return strings.HasPrefix(path, "@")
+}
+
+// removeStaleSocket removes the socket file left by a process that no longer listens on it.
+func removeStaleSocket(path string) error {
+	if isAbstractSocket(path) {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *wire) listen() (listener net.Listener, err error) {
+	if w.listener != nil {
+		listener = w.listener
+	} else {
+		listener, err = w.transport.Listen(w.addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if w.tlsConfig != nil && !isWebSocketAddr(w.addr) {
+		listener = tls.NewListener(listener, w.tlsConfig)
+	}
+	return listener, nil
+}
+
+func (w *wire) dial(ctx context.Context, addr string) (conn net.Conn, err error) {
+	if w.dialer != nil {
+		conn, err = w.dialer(ctx, addr)
+	} else {
+		conn, err = w.transport.Dial(ctx, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w.tlsConfig != nil && !isWebSocketAddr(addr) {
+		conn, err = tlsClient(ctx, conn, addr, w.tlsConfig)
+	}
+	return conn, err
+}
+
+func tlsClient(ctx context.Context, conn net.Conn, addr string, conf *tls.Config) (net.Conn, error) {
+	if len(conf.ServerName) == 0 && !conf.InsecureSkipVerify {
+		if host := tlsServerName(addr); len(host) > 0 {
+			conf = conf.Clone()
+			conf.ServerName = host
+		}
+	}
+	tlsConn := tls.Client(conn, conf)
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := tlsConn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		defer tlsConn.SetDeadline(time.Time{})
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// tlsServerName returns the server name verified on the client side, the Unix domain socket is the local host.
+func tlsServerName(addr string) string {
+	if _, ok := unixSocketPath(addr); ok {
+		return "localhost"
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
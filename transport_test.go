@@ -0,0 +1,403 @@
+package wirenet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, errors.New("pipe listener closed")
+	}
+}
+
+func (l *pipeListener) Close() error {
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: "pipe", Net: "pipe"}
+}
+
+func (l *pipeListener) Listen(_ string) (net.Listener, error) {
+	return l, nil
+}
+
+func (l *pipeListener) Dial(ctx context.Context, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, errors.New("pipe listener closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestWire_ConnectTransport(t *testing.T) {
+	transport := newPipeListener()
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+	payload := []byte("payload")
+
+	// server side
+	server, err := Mount("pipe", WithTransport(transport), WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	server.Stream("echo", func(ctx context.Context, s Stream) {
+		buf := bytes.NewBuffer(nil)
+		_, err := s.WriteTo(buf)
+		assert.Nil(t, err)
+		_, err = s.ReadFrom(buf)
+		assert.Nil(t, err)
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join("pipe", WithTransport(transport), WithSessionOpenHook(func(s Session) {
+		initCli <- s
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("echo")
+	assert.Nil(t, err)
+	_, err = stream.ReadFrom(bytes.NewReader(payload))
+	assert.Nil(t, err)
+	buf := bytes.NewBuffer(nil)
+	_, err = stream.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, buf.Bytes())
+	assert.Nil(t, stream.Close())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectListenerDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	initCli := make(chan struct{})
+
+	// server side
+	server, err := Mount("", WithListener(listener))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+
+	// client side
+	var dialed bool
+	client, err := Join("",
+		WithDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			dialed = true
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", listener.Addr().String())
+		}),
+		WithSessionOpenHook(func(s Session) {
+			close(initCli)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	assert.True(t, dialed)
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectListenerTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	initCli := make(chan struct{})
+
+	// server side
+	serverTLSConf, err := LoadCertificates("server", "./certs")
+	assert.Nil(t, err)
+	server, err := Mount("", WithListener(listener), WithTLS(serverTLSConf))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+
+	// client side
+	clientTLSConf, err := LoadCertificates("client", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf.InsecureSkipVerify = true
+	client, err := Join(listener.Addr().String(),
+		WithTLS(clientTLSConf),
+		WithSessionOpenHook(func(s Session) {
+			close(initCli)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	path, ok := unixSocketPath("unix:///tmp/wire.sock")
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/wire.sock", path)
+
+	path, ok = unixSocketPath("unix:@wire")
+	assert.True(t, ok)
+	assert.Equal(t, "@wire", path)
+
+	_, ok = unixSocketPath(":8989")
+	assert.False(t, ok)
+	_, ok = unixSocketPath("unix://")
+	assert.False(t, ok)
+}
+
+func TestWire_ConnectUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wirenet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wire.sock")
+	addr := "unix://" + path
+
+	// stale socket file
+	stale, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.Nil(t, stale.Close())
+	_, err = os.Stat(path)
+	assert.Nil(t, err)
+
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// server side
+	server, err := Mount(addr,
+		WithSocketFileMode(0600),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		close(initCli)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+	<-done
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNetTransport_ListenUnixMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wirenet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wire.sock")
+	transport := &netTransport{socketMode: 0600}
+
+	listener, err := transport.Listen("unix://" + path)
+	assert.Nil(t, err)
+	assert.Equal(t, path, listener.Addr().String())
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the socket of the running listener is not replaced
+	_, err = transport.Listen("unix://" + path)
+	assert.NotNil(t, err)
+
+	// the temporary directories are removed
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	assert.Nil(t, listener.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWire_SocketModeConflictingOptions(t *testing.T) {
+	w, err := Mount("unix:///tmp/wire.sock", WithSocketFileMode(0600), WithTransport(newPipeListener()))
+	assert.Nil(t, w)
+	assert.True(t, errors.Is(err, ErrConflictingOptions))
+
+	w, err = Mount("", WithSocketFileMode(0600), WithListener(newPipeListener()))
+	assert.Nil(t, w)
+	assert.True(t, errors.Is(err, ErrConflictingOptions))
+}
+
+func TestTLSServerName(t *testing.T) {
+	assert.Equal(t, "example.com", tlsServerName("example.com:8989"))
+	assert.Equal(t, "localhost", tlsServerName("unix:///tmp/wire.sock"))
+	assert.Equal(t, "localhost", tlsServerName("unix:@wire"))
+	assert.Equal(t, "", tlsServerName("example.com"))
+}
+
+// localhostCert returns the self-signed certificate of localhost and the pool with it.
+func localhostCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestWire_ConnectUnixSocketTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wirenet")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	addr := "unix://" + filepath.Join(dir, "wire.sock")
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+	cert, pool := localhostCert(t)
+
+	// server side
+	server, err := Mount(addr,
+		WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side, the certificate is verified for localhost
+	client, err := Join(addr,
+		WithTLS(&tls.Config{RootCAs: pool}),
+		WithRetryMax(1),
+		WithSessionOpenHook(func(s Session) {
+			close(initCli)
+		}))
+	assert.Nil(t, err)
+	connErr := make(chan error, 1)
+	go func() {
+		connErr <- client.Connect()
+	}()
+	select {
+	case <-initCli:
+	case err := <-connErr:
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, <-connErr)
+	assert.Nil(t, server.Close())
+}
+
+func TestWire_ConnectAbstractSocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract sockets are supported only on linux")
+	}
+	addr := "unix:@wirenet-" + uuid.New().String()
+	initSrv := make(chan struct{})
+	initCli := make(chan struct{})
+
+	// server side
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr, WithSessionOpenHook(func(s Session) {
+		close(initCli)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	<-initCli
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
@@ -0,0 +1,250 @@
+package wirenet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsScheme  = "ws://"
+	wssScheme = "wss://"
+)
+
+// WebSocketListener is the http.Handler that upgrades requests into the wirenet connections.
+// It implements net.Listener and is used on the server side with WithListener(),
+// so the wire can be served inside an existing http.Server.
+type WebSocketListener struct {
+	upgrader websocket.Upgrader
+	conns    chan net.Conn
+	done     chan struct{}
+	once     sync.Once
+	addr     net.Addr
+}
+
+// NewWebSocketListener constructs a new WebSocketListener.
+func NewWebSocketListener() *WebSocketListener {
+	return &WebSocketListener{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  BufSize,
+			WriteBufferSize: BufSize,
+		},
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+		addr:  wsAddr("websocket"),
+	}
+}
+
+// ServeHTTP upgrades the request and passes the connection to Accept().
+func (l *WebSocketListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-l.done:
+		http.Error(w, ErrWireClosed.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	conn, err := l.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	wsConn := newWebSocketConn(conn)
+	select {
+	case l.conns <- wsConn:
+	case <-l.done:
+		wsConn.Close()
+	case <-r.Context().Done():
+		wsConn.Close()
+	}
+}
+
+// Accept waits for and returns the next upgraded connection.
+func (l *WebSocketListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, ErrWireClosed
+	}
+}
+
+// Close stops accepting the connections. Upgraded connections are not closed.
+func (l *WebSocketListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+// Addr returns the listener's network address.
+func (l *WebSocketListener) Addr() net.Addr {
+	return l.addr
+}
+
+type wsAddr string
+
+func (a wsAddr) Network() string {
+	return "websocket"
+}
+
+func (a wsAddr) String() string {
+	return string(a)
+}
+
+type webSocketConn struct {
+	conn *websocket.Conn
+	r    io.Reader
+	rmu  sync.Mutex
+	wmu  sync.Mutex
+}
+
+func newWebSocketConn(conn *websocket.Conn) net.Conn {
+	return &webSocketConn{
+		conn: conn,
+	}
+}
+
+func (c *webSocketConn) Read(p []byte) (n int, err error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	for {
+		if c.r == nil {
+			typ, r, err := c.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			if typ != websocket.BinaryMessage {
+				continue
+			}
+			c.r = r
+		}
+		n, err = c.r.Read(p)
+		if err == io.EOF {
+			c.r = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *webSocketConn) Write(p []byte) (n int, err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *webSocketConn) Close() error {
+	c.wmu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+	c.wmu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *webSocketConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *webSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *webSocketConn) SetDeadline(t time.Time) error {
+	if err := c.conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *webSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *webSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func isWebSocketAddr(addr string) bool {
+	return strings.HasPrefix(addr, wsScheme) || strings.HasPrefix(addr, wssScheme)
+}
+
+// listenWebSocket serves the WebSocketListener by the address like ws://host:port/path.
+func listenWebSocket(addr string, conf *tls.Config) (net.Listener, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" && conf == nil {
+		return nil, errors.New("wirenet: wss requires the TLS config, see WithTLS()")
+	}
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		listener = tls.NewListener(listener, conf)
+	}
+
+	path := u.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	wsListener := NewWebSocketListener()
+	wsListener.addr = listener.Addr()
+	mux := http.NewServeMux()
+	mux.Handle(path, wsListener)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	return &webSocketServer{WebSocketListener: wsListener, srv: srv}, nil
+}
+
+type webSocketServer struct {
+	*WebSocketListener
+	srv *http.Server
+}
+
+func (s *webSocketServer) Close() error {
+	_ = s.WebSocketListener.Close()
+	return s.srv.Close()
+}
+
+// dialWebSocket connects to the address like ws://host:port/path or wss://host:port/path.
+func dialWebSocket(ctx context.Context, addr string, netDial Dialer, conf *tls.Config) (net.Conn, error) {
+	dialer := &websocket.Dialer{
+		ReadBufferSize:  BufSize,
+		WriteBufferSize: BufSize,
+		TLSClientConfig: conf,
+		NetDialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return netDial(ctx, addr)
+		},
+	}
+	conn, resp, err := dialer.DialContext(ctx, addr, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("wirenet: websocket handshake %s: %w", resp.Status, err)
+		}
+		return nil, err
+	}
+	return newWebSocketConn(conn), nil
+}
@@ -0,0 +1,101 @@
+package wirenet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWire_ConnectWebSocket(t *testing.T) {
+	addr := "ws://127.0.0.1" + genAddr(t) + "/wire"
+	testWebSocketEcho(t, addr, addr, nil, nil)
+}
+
+func TestWire_ConnectWebSocketTLS(t *testing.T) {
+	addr := "wss://127.0.0.1" + genAddr(t) + "/wire"
+	serverTLSConf, err := LoadCertificates("server", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf, err := LoadCertificates("client", "./certs")
+	assert.Nil(t, err)
+	clientTLSConf.InsecureSkipVerify = true
+	testWebSocketEcho(t, addr, addr,
+		[]Option{WithTLS(serverTLSConf)},
+		[]Option{WithTLS(clientTLSConf)})
+}
+
+func TestWebSocketListener(t *testing.T) {
+	listener := NewWebSocketListener()
+	mux := http.NewServeMux()
+	mux.Handle("/wire", listener)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tokenErr := errors.New("token invalid")
+	addr := "ws://" + strings.TrimPrefix(srv.URL, "http://") + "/wire"
+	testWebSocketEcho(t, "", addr,
+		[]Option{
+			WithListener(listener),
+			WithTokenValidator(func(streamName string, id Identification, token Token) error {
+				if bytes.Equal(token, Token("token")) {
+					return nil
+				}
+				return tokenErr
+			}),
+		},
+		[]Option{WithIdentification(Identification("ws"), Token("token"))})
+}
+
+func testWebSocketEcho(t *testing.T, srvAddr, cliAddr string, srvOpts, cliOpts []Option) {
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+	payload := []byte("payload")
+
+	// server side
+	srvOpts = append(srvOpts, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	server, err := Mount(srvAddr, srvOpts...)
+	assert.Nil(t, err)
+	server.Stream("echo", func(ctx context.Context, s Stream) {
+		buf := bytes.NewBuffer(nil)
+		_, err := s.WriteTo(buf)
+		assert.Nil(t, err)
+		_, err = s.ReadFrom(buf)
+		assert.Nil(t, err)
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	cliOpts = append(cliOpts, WithSessionOpenHook(func(s Session) {
+		initCli <- s
+	}))
+	client, err := Join(cliAddr, cliOpts...)
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("echo")
+	assert.Nil(t, err)
+	_, err = stream.ReadFrom(bytes.NewReader(payload))
+	assert.Nil(t, err)
+	buf := bytes.NewBuffer(nil)
+	_, err = stream.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, buf.Bytes())
+	assert.Nil(t, stream.Close())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, server.Close())
+}
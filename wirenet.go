@@ -4,10 +4,10 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -48,7 +48,7 @@ type (
 // Wire is used to initialize a server or client side connection.
 type Wire interface {
 
-	// Sessions returns a list of active sessions.
+	// Sessions returns a copy of the list of active sessions.
 	Sessions() Sessions
 
 	// Session returns the session by UUID.
@@ -58,16 +58,43 @@ type Wire interface {
 	// If a named stream already exists, stream overwrite.
 	Stream(name string, h Handler)
 
+	// Method registers the RPC method for the given name like "svc.Method", see Call().
+	// The fn must be func(context.Context, *Req) (*Resp, error), otherwise ErrInvalidMethod is returned.
+	Method(name string, fn interface{}) error
+
+	// StreamMethod registers the streaming RPC method for the given name, see CallStream().
+	// The fn must be func(context.Context, *Req, ServerStream) error for the server-streaming method
+	// or func(context.Context, ServerStream) error for the bidirectional streaming method,
+	// otherwise ErrInvalidMethod is returned.
+	StreamMethod(name string, fn interface{}) error
+
 	// Close gracefully shutdown the server without interrupting any active connections.
 	Close() error
 
+	// Shutdown gracefully shutdown the wire, waits for the active streams until the context is done
+	// and then closes them. If some streams were closed, the *ShutdownError is returned.
+	Shutdown(ctx context.Context) error
+
+	// State returns the connection state of the wire.
+	State() (State, StateInfo)
+
+	// Endpoint returns the address of the currently connected server on the client side
+	// or the listening address on the server side.
+	// Returns an empty string if the wire is not connected.
+	Endpoint() string
+
 	// Connect creates a new connection.
 	// If the wire is on the client-side, then used dial().
 	// If the wire is on the server-side, then used listener().
 	Connect() error
+
+	// ConnectContext creates a new connection like Connect().
+	// When the context is done, the wire stops retrying or listening, closes and returns the context error.
+	ConnectContext(ctx context.Context) error
 }
 
 // Mount constructs a new connection point with the given addr and Options as the server side.
+// The addr can be a TCP address or a Unix domain socket like unix:///path/to.sock or unix:@abstract.
 func Mount(addr string, opts ...Option) (Wire, error) {
 	return newWire(addr, serverSide, opts...)
 }
@@ -85,6 +112,13 @@ func Join(addr string, opts ...Option) (Wire, error) {
 	return newWire(addr, clientSide, opts...)
 }
 
+// JoinAny constructs a new connection point with the given addrs and Options as the client side.
+// The addrs are dialed according to the endpoint policy, see WithEndpointPolicy().
+func JoinAny(addrs []string, opts ...Option) (Wire, error) {
+	opts = append([]Option{WithEndpoints(addrs...)}, opts...)
+	return newWire("", clientSide, opts...)
+}
+
 const (
 	clientSide role = 1
 	serverSide role = 2
@@ -115,6 +149,15 @@ func (s role) String() (side string) {
 type wire struct {
 	addr string
 
+	endpoints       []string
+	endpointPolicy  EndpointPolicy
+	endpointNext    int
+	endpoint        string
+	healthyEndpoint string
+	dialTimeout     time.Duration
+	srv             *srvRecord
+	lookupSRV       lookupSRVFunc
+
 	readTimeout      time.Duration
 	writeTimeout     time.Duration
 	sessCloseTimeout time.Duration
@@ -125,58 +168,84 @@ type wire struct {
 	onConnect     func(io.Closer)
 	transportConf *yamux.Config
 
+	state       State
+	stateInfo   StateInfo
+	stateHook   StateHook
 	hubMode     bool
 	closed      bool
 	conn        bool
 	connCounter int
-	closeCh     chan chan *ShutdownError
+	closeCh     chan *shutdownRequest
 	waitCh      chan struct{}
 
-	retryWaitMin time.Duration
-	retryWaitMax time.Duration
-	retryMax     int
-	retryPolicy  RetryPolicy
+	retryWaitMin    time.Duration
+	retryWaitMax    time.Duration
+	retryMax        int
+	retryResetAfter time.Duration
+	resumeGrace     time.Duration
+	detached        *session
+	retryPolicy     RetryPolicy
+	isRetryable     RetryableError
 
 	sessions    Sessions
-	streamIndex map[string]Session
+	streamIndex map[string][]Session
+	peerIndex   map[peerStream]Session
+	balancer    Balancer
+	hubPolicy   HubPolicy
 
 	token          Token
 	verifyToken    TokenValidator
 	identification Identification
 
-	tlsConfig *tls.Config
+	tlsConfig  *tls.Config
+	transport  Transport
+	listener   net.Listener
+	dialer     Dialer
+	socketMode os.FileMode
+	proxy      proxyFunc
 
-	handlers     map[string]Handler
-	errorHandler ErrorHandler
-	mu           sync.RWMutex
+	handlers         map[string]Handler
+	forwardValidator ForwardValidator
+	methods          map[string]*rpcMethod
+	streamMethods    map[string]*rpcStreamMethod
+	rpcCodec         Codec
+	errorHandler     ErrorHandler
+	mu               sync.RWMutex
 }
 
 func newWire(addr string, role role, opts ...Option) (Wire, error) {
-	if len(addr) == 0 {
-		return nil, ErrAddrEmpty
-	}
 	wire := &wire{
-		addr:         addr,
-		handlers:     make(map[string]Handler),
-		errorHandler: func(ctx context.Context, err error) {},
+		addr:          addr,
+		handlers:      make(map[string]Handler),
+		methods:       make(map[string]*rpcMethod),
+		streamMethods: make(map[string]*rpcStreamMethod),
+		rpcCodec:      JSONCodec,
+		errorHandler:  func(ctx context.Context, err error) {},
 
 		readTimeout:      DefaultReadTimeout,
 		writeTimeout:     DefaultWriteTimeout,
 		sessCloseTimeout: DefaultSessionCloseTimeout,
 
 		sessions:    make(Sessions),
-		streamIndex: make(map[string]Session),
+		streamIndex: make(map[string][]Session),
+		peerIndex:   make(map[peerStream]Session),
+		balancer:    RoundRobinBalancer(),
 
 		role:          role,
 		openSessHook:  func(Session) {},
 		closeSessHook: func(Session) {},
-		closeCh:       make(chan chan *ShutdownError),
+		closeCh:       make(chan *shutdownRequest),
 		onConnect:     func(_ io.Closer) {},
+		stateHook:     func(State, StateInfo) {},
 
 		retryMax:     DefaultRetryMax,
 		retryWaitMin: DefaultRetryWaitMin,
 		retryWaitMax: DefaultRetryWaitMax,
 		retryPolicy:  DefaultRetryPolicy,
+		isRetryable:  DefaultRetryableError,
+
+		dialTimeout: DefaultDialTimeout,
+		lookupSRV:   net.DefaultResolver.LookupSRV,
 
 		transportConf: &yamux.Config{
 			AcceptBacklog:          DefaultAcceptBacklog,
@@ -193,6 +262,25 @@ func newWire(addr string, role role, opts ...Option) (Wire, error) {
 		}
 		opt(wire)
 	}
+	if len(addr) == 0 && len(wire.endpoints) == 0 && wire.srv == nil &&
+		wire.listener == nil && wire.dialer == nil {
+		return nil, ErrAddrEmpty
+	}
+	if wire.socketMode != 0 && (wire.transport != nil || wire.listener != nil) {
+		// the socket is bound by the default transport only
+		return nil, fmt.Errorf("%w: the socket file mode with the custom transport or listener", ErrConflictingOptions)
+	}
+	if wire.proxy != nil && (wire.transport != nil || wire.dialer != nil) {
+		// the proxy is dialed by the default transport only
+		return nil, fmt.Errorf("%w: the proxy with the custom transport or dialer", ErrConflictingOptions)
+	}
+	if wire.transport == nil {
+		wire.transport = &netTransport{
+			socketMode: wire.socketMode,
+			tlsConfig:  wire.tlsConfig,
+			proxy:      wire.proxy,
+		}
+	}
 	return wire, nil
 }
 
@@ -209,16 +297,37 @@ func (w *wire) Session(sid uuid.UUID) (Session, error) {
 func (w *wire) Sessions() Sessions {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
-	return w.sessions
+	sessions := make(Sessions, len(w.sessions))
+	for sid, sess := range w.sessions {
+		sessions[sid] = sess
+	}
+	return sessions
 }
 
 func (w *wire) Connect() (err error) {
+	return w.ConnectContext(context.Background())
+}
+
+func (w *wire) ConnectContext(ctx context.Context) (err error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+		case <-done:
+		}
+	}()
+
 	switch w.role {
 	case clientSide:
-		err = w.acceptClient()
+		err = w.acceptClient(ctx)
 	case serverSide:
 		err = w.acceptServer()
 	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
 	return err
 }
 
@@ -250,19 +359,6 @@ func (w *wire) findHandler(name string) (Handler, error) {
 	return h, nil
 }
 
-func (w *wire) findSession(name string) (Session, error) {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	if len(w.sessions) == 0 || w.closed {
-		return nil, ErrSessionClosed
-	}
-	sess, found := w.streamIndex[name]
-	if !found {
-		return nil, ErrSessionNotFound
-	}
-	return sess, nil
-}
-
 func (w *wire) isConnOk() bool {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
@@ -276,6 +372,14 @@ func (w *wire) setConnFlag(flag bool) {
 }
 
 func (w *wire) Close() (err error) {
+	return w.closeContext(nil)
+}
+
+func (w *wire) Shutdown(ctx context.Context) (err error) {
+	return w.closeContext(ctx)
+}
+
+func (w *wire) closeContext(ctx context.Context) (err error) {
 	closeup := func() {
 		w.mu.Lock()
 		w.closed = true
@@ -293,116 +397,228 @@ func (w *wire) Close() (err error) {
 
 	closeup()
 
-	return w.close()
+	return w.close(ctx)
 }
 
-func (w *wire) close() (err error) {
-	errCh := make(chan *ShutdownError)
-	w.closeCh <- errCh
-	shutErr := <-errCh
-	if shutErr.IsFilled() {
+// shutdownRequest is used to close the sessions gracefully until the context is done.
+// If the context is nil, the session close timeout is used for each session.
+// If the detach flag is set, the client side connection is closed, but the sessions are kept for resumption.
+type shutdownRequest struct {
+	ctx    context.Context
+	detach bool
+	errCh  chan *ShutdownError
+}
+
+func (w *wire) close(ctx context.Context) (err error) {
+	req := &shutdownRequest{
+		ctx:   ctx,
+		errCh: make(chan *ShutdownError),
+	}
+	if !w.sendShutdown(req) {
+		return nil
+	}
+	shutErr := <-req.errCh
+	if shutErr.IsFilled() || ctx != nil && shutErr.IsCut() {
 		err = shutErr
 	}
 	return err
 }
 
-func (w *wire) acceptClient() (err error) {
-	tryClose := func(c io.Closer) {
+// sendShutdown sends the request to the shutdown of the current connection.
+// Returns a false flag if the connection is already shut down on the client side.
+func (w *wire) sendShutdown(req *shutdownRequest) bool {
+	w.mu.RLock()
+	closeCh, waitCh := w.closeCh, w.waitCh
+	w.mu.RUnlock()
+	select {
+	case closeCh <- req:
+		return true
+	case <-waitCh:
+		return false
+	}
+}
+
+// connected marks the connection or the listener as up and starts its shutdown,
+// so the wire can be closed while the session is opening.
+// Returns a false flag if the wire is closed while dialing or listening.
+func (w *wire) connected(conn io.Closer, endpoint string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return false
+	}
+	w.closeCh = make(chan *shutdownRequest)
+	w.waitCh = make(chan struct{})
+	w.conn = true
+	w.endpoint = endpoint
+	go w.shutdown(conn, w.closeCh, w.waitCh)
+	return true
+}
+
+func (w *wire) acceptClient(ctx context.Context) (err error) {
+	tryClose := func() {
 		w.setConnFlag(false)
-		c.Close()
+		// the connection is closed by the shutdown, unless the wire is already closing
+		req := &shutdownRequest{
+			detach: true,
+			errCh:  make(chan *ShutdownError),
+		}
+		if w.sendShutdown(req) {
+			<-req.errCh
+		}
 	}
 
+	defer func() {
+		if sess := w.takeDetached(); sess != nil {
+			_ = sess.Close()
+		}
+		w.setState(StateClosed, StateInfo{
+			Attempt: w.connCounter,
+			Err:     err,
+		})
+	}()
+
+	state := StateConnecting
 	for {
 		attemptNum := w.connCounter
-		if attemptNum >= w.retryMax || w.isClosed() {
+		if w.isRetryLimit(attemptNum) || w.isClosed() || ctx.Err() != nil {
 			break
 		}
 
 		w.connCounter++
 		w.setConnFlag(false)
+		w.setState(state, StateInfo{Attempt: w.connCounter})
+		state = StateReconnecting
 
-		conn, dialErr := w.dial()
+		conn, endpoint, dialErr := w.dialEndpoints(ctx)
 		if dialErr != nil {
 			err = dialErr
-			if isNotConnErr(dialErr) {
+			if !w.isRetryable(dialErr) {
 				break
 			}
-
-			retryWait := w.retryPolicy(
-				w.retryWaitMin,
-				w.retryWaitMax,
-				attemptNum)
-
-			timeout := time.Now().Add(retryWait)
-			for {
-				if (timeout.Unix() <= time.Now().Unix()) || w.isClosed() {
-					break
-				}
-				time.Sleep(500 * time.Millisecond)
+			if waitErr := w.waitRetry(ctx, attemptNum, dialErr); waitErr != nil {
+				return waitErr
 			}
 			continue
 		}
 
-		w.setConnFlag(true)
-		w.connCounter = 0
+		closer := &connCloser{conn: conn}
+		if !w.connected(closer, endpoint) {
+			_ = conn.Close()
+			break
+		}
+		w.mu.RLock()
+		waitCh := w.waitCh
+		w.mu.RUnlock()
+		connectedAt := time.Now()
 
 		wrapConn, serveErr := yamux.Client(conn, w.transportConf)
 		if serveErr != nil {
-			tryClose(conn)
+			tryClose()
 			return serveErr
 		}
+		closer.set(wrapConn)
 
-		sid, remoteStreamNames, sErr := w.openSession(wrapConn)
+		h, sErr := w.openSession(wrapConn)
 		if sErr != nil {
-			tryClose(conn)
+			tryClose()
 			return sErr
 		}
 
-		w.waitCh = make(chan struct{})
-		w.closeCh = make(chan chan *ShutdownError)
-
-		go w.shutdown(wrapConn)
-
-		openSession(sid, w.identification, wrapConn, w, remoteStreamNames)
+		detached := w.takeDetached()
+		if h.resumed != nil {
+			go h.resumed.accept()
+		} else {
+			if detached != nil {
+				_ = detached.Close()
+			}
+			openSession(h.sid, w.identification, wrapConn, w, h.streamNames, h.ticket)
+		}
+		w.setState(StateConnected, StateInfo{
+			Attempt:  attemptNum + 1,
+			Endpoint: endpoint,
+		})
 		go w.onConnect(w)
 
-		<-w.waitCh
+		<-waitCh
+		w.setEndpoint("")
+		if w.isClosed() {
+			continue
+		}
+		// the session dropped, the wire can be closed while waiting for the next attempt
+		w.setConnFlag(false)
+
+		// the session dropped after being healthy starts retrying from the first attempt,
+		// otherwise the attempts continue with the backoff.
+		if time.Since(connectedAt) >= w.retryResetAfter {
+			w.connCounter = 0
+			continue
+		}
+		if w.isRetryLimit(w.connCounter) {
+			continue
+		}
+		if waitErr := w.waitRetry(ctx, attemptNum, nil); waitErr != nil {
+			return waitErr
+		}
 	}
 	return err
 }
 
-func (w *wire) listen() (listener net.Listener, err error) {
-	if w.tlsConfig != nil {
-		listener, err = tls.Listen("tcp", w.addr, w.tlsConfig)
-	} else {
-		listener, err = net.Listen("tcp", w.addr)
-	}
-	return listener, err
+// isRetryLimit returns a true flag if the attempt number reached the retry max.
+func (w *wire) isRetryLimit(attemptNum int) bool {
+	return w.retryMax != RetryForever && attemptNum >= w.retryMax
 }
 
-func (w *wire) dial() (conn net.Conn, err error) {
-	if w.tlsConfig != nil {
-		conn, err = tls.Dial("tcp", w.addr, w.tlsConfig)
-	} else {
-		conn, err = net.Dial("tcp", w.addr)
+// waitRetry waits for the next connection attempt computed by the retry policy.
+func (w *wire) waitRetry(ctx context.Context, attemptNum int, lastErr error) error {
+	retryWait := w.retryPolicy(
+		w.retryWaitMin,
+		w.retryWaitMax,
+		attemptNum)
+
+	timeout := time.Now().Add(retryWait)
+	w.setState(StateReconnecting, StateInfo{
+		Attempt:   w.connCounter,
+		Err:       lastErr,
+		NextRetry: timeout,
+	})
+	for {
+		left := time.Until(timeout)
+		if left <= 0 || w.isClosed() {
+			return nil
+		}
+		if left > 500*time.Millisecond {
+			left = 500 * time.Millisecond
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(left):
+		}
 	}
-	return
 }
 
 func (w *wire) acceptServer() (err error) {
+	w.setState(StateConnecting, StateInfo{})
 	listener, err := w.listen()
 	if err != nil {
+		w.setState(StateClosed, StateInfo{Err: err})
 		return err
 	}
 	defer func() {
 		listener.Close()
 		w.setConnFlag(false)
+		w.setEndpoint("")
+		w.setState(StateClosed, StateInfo{Err: err})
 	}()
 
-	go w.shutdown(listener)
+	// the wire is closed while listening
+	if !w.connected(listener, listener.Addr().String()) {
+		return nil
+	}
 	go w.onConnect(w)
 
-	w.setConnFlag(true)
+	w.setState(StateConnected, StateInfo{Endpoint: listener.Addr().String()})
 
 	for {
 		conn, acceptErr := listener.Accept()
@@ -425,48 +641,85 @@ func (w *wire) acceptServer() (err error) {
 			break
 		}
 
-		sid, id, localStreamNames, sErr := w.confirmSession(wrapConn, w.verifyToken)
+		h, sErr := w.confirmSession(wrapConn)
 		if sErr != nil {
 			conn.Close()
+		}
+		if h.resumed != nil {
+			// the resumed session is detached again if the connection is closed
+			go h.resumed.accept()
+			continue
+		}
+		if sErr != nil {
 			continue
 		}
 
-		openSession(sid, id, wrapConn, w, localStreamNames)
+		openSession(h.sid, h.identification, wrapConn, w, h.streamNames, h.ticket)
 	}
 	return err
 }
 
-func (w *wire) shutdown(conn io.Closer) {
-	errCh, ok := <-w.closeCh
+// shutdown closes the connection on the request, the wait channel is closed on the client side.
+func (w *wire) shutdown(conn io.Closer, closeCh chan *shutdownRequest, waitCh chan struct{}) {
+	req, ok := <-closeCh
 	if !ok {
 		return
 	}
 
 	shutdownErr := NewShutdownError()
 	w.mu.RLock()
-	sessions := make([]Session, 0, len(w.sessions))
-	for _, sess := range w.sessions {
-		sessions = append(sessions, sess)
+	sessions := make([]*session, 0, len(w.sessions))
+	if !req.detach {
+		for _, sess := range w.sessions {
+			sessions = append(sessions, sess.(*session))
+		}
 	}
 	w.mu.RUnlock()
 
-	var err error
 	for _, sess := range sessions {
-		err = sess.Close()
+		ctx, cancel := req.ctx, context.CancelFunc(func() {})
+		if ctx == nil {
+			ctx, cancel = context.WithTimeout(context.Background(), sess.timeoutDur)
+		}
+		cut, err := sess.close(ctx)
+		cancel()
 		if err != nil && err != ErrSessionClosed {
 			shutdownErr.Add(err)
 		}
+		if len(cut) > 0 {
+			shutdownErr.AddCut(sess.ID(), cut)
+		}
 	}
 
 	if err := conn.Close(); err != nil {
 		shutdownErr.Add(err)
 	}
 
-	if w.role.IsClientSide() {
-		close(w.waitCh)
+	if waitCh != nil {
+		close(waitCh)
 	}
 
-	errCh <- shutdownErr
+	req.errCh <- shutdownErr
+}
+
+// connCloser closes the multiplexed connection of the client side,
+// or the raw connection if the multiplexer is not started yet.
+type connCloser struct {
+	conn io.Closer
+	mu   sync.Mutex
+}
+
+func (c *connCloser) set(conn io.Closer) {
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+}
+
+func (c *connCloser) Close() error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	return conn.Close()
 }
 
 func (w *wire) registerSession(s Session) {
@@ -474,8 +727,12 @@ func (w *wire) registerSession(s Session) {
 	defer w.mu.Unlock()
 	w.sessions[s.ID()] = s
 	if w.hubMode {
+		id := string(s.Identification())
 		for _, streamName := range s.StreamNames() {
-			w.streamIndex[streamName] = s
+			w.addProvider(streamName, s)
+			if len(id) > 0 {
+				w.peerIndex[peerStream{id: id, stream: streamName}] = s
+			}
 		}
 	}
 }
@@ -484,64 +741,96 @@ func (w *wire) unregisterSession(s Session) {
 	var isEmptySessions bool
 	w.mu.Lock()
 	if w.hubMode {
+		id := string(s.Identification())
 		for _, streamName := range s.StreamNames() {
-			delete(w.streamIndex, streamName)
+			w.removeProvider(streamName, s)
+			// the client can be reconnected with the new session before the old one is closed
+			peer := peerStream{id: id, stream: streamName}
+			if w.peerIndex[peer] == s {
+				delete(w.peerIndex, peer)
+			}
 		}
 	}
 	delete(w.sessions, s.ID())
 	isEmptySessions = len(w.sessions) == 0
 	w.mu.Unlock()
 
-	if w.role.IsClientSide() && isEmptySessions && !w.isClosed() {
-		w.close()
+	// the connection of the detached session is already closed
+	isDetached := s.(*session).isDetached()
+	if w.role.IsClientSide() && isEmptySessions && !isDetached && !w.isClosed() {
+		w.close(nil)
 	}
 }
 
-func (w *wire) openSession(conn *yamux.Session) (sid uuid.UUID, rsn []string, err error) {
+func (w *wire) openSession(conn *yamux.Session) (h handshake, err error) {
 	stream, err := conn.OpenStream()
 	if err != nil {
-		return sid, rsn, err
+		return h, err
 	}
 	defer stream.Close()
 
 	if err := stream.SetDeadline(deadline(w)); err != nil {
-		return sid, rsn, err
+		return h, err
+	}
+
+	// the detached session is resumed with the same id
+	sid := uuid.New()
+	var ticket []byte
+	w.mu.RLock()
+	detached := w.detached
+	w.mu.RUnlock()
+	if detached != nil {
+		sid = detached.ID()
+		ticket = detached.resumeTicket()
 	}
 
-	sid = uuid.New()
 	sessID, err := sid.MarshalBinary()
 	if err != nil {
-		return sid, rsn, err
+		return h, err
 	}
 
-	remoteStreamNames, err := openSessionRequest(
+	resp, err := openSessionRequest(
 		stream,
 		sessID,
 		w.token,
 		w.identification,
-		localStreamNames(w.handlers))
+		localStreamNames(w.handlers),
+		ticket)
 	if err != nil {
-		return sid, nil, err
+		return h, err
 	}
 
-	return sid, remoteStreamNames, nil
+	h.sid, err = uuid.FromBytes(resp.Sid)
+	if err != nil {
+		return h, err
+	}
+	h.identification = w.identification
+	h.streamNames = resp.RemoteStreamNames
+	h.ticket = resp.ResumeTicket
+	if resp.Resumed && detached != nil && detached.rebind(conn, ticket, resp.ResumeTicket) {
+		h.resumed = detached
+	}
+	return h, nil
 }
 
-func (w *wire) confirmSession(conn *yamux.Session, tv TokenValidator) (sid uuid.UUID, id Identification, lsn []string, err error) {
+func (w *wire) confirmSession(conn *yamux.Session) (h handshake, err error) {
 	stream, err := conn.AcceptStream()
 	if err != nil {
-		return sid, nil, nil, err
+		return h, err
 	}
 	defer stream.Close()
 
 	if err := stream.SetDeadline(deadline(w)); err != nil {
-		return sid, nil, lsn, err
+		return h, err
 	}
 
 	return confirmSessionRequest(
 		stream,
 		w.verifyToken,
 		localStreamNames(w.handlers),
+		func(req *pb.OpenSessionRequest, resp *pb.OpenSessionResponse) (*session, error) {
+			return w.resumeSession(conn, req, resp)
+		},
 	)
 }
 
@@ -557,14 +846,17 @@ func deadline(w *wire) time.Time {
 	return time.Now().Add(w.transportConf.ConnectionWriteTimeout)
 }
 
-func confirmSessionRequest(conn *yamux.Stream, fn TokenValidator, localStreamNames []string) (sid uuid.UUID, id Identification, lsn []string, err error) {
+// resumeFunc fills the resumption fields of the response and returns the resumed session.
+type resumeFunc func(req *pb.OpenSessionRequest, resp *pb.OpenSessionResponse) (*session, error)
+
+func confirmSessionRequest(conn *yamux.Stream, fn TokenValidator, localStreamNames []string, resume resumeFunc) (h handshake, err error) {
 	frm, err := newDecoder(conn).Decode()
 	if err != nil {
-		return sid, nil, nil, err
+		return h, err
 	}
 	var req pb.OpenSessionRequest
 	if err := proto.Unmarshal(frm.Payload(), &req); err != nil {
-		return sid, nil, nil, err
+		return h, err
 	}
 
 	resp := &pb.OpenSessionResponse{
@@ -576,23 +868,33 @@ func confirmSessionRequest(conn *yamux.Stream, fn TokenValidator, localStreamNam
 			resp.Err = err.Error()
 		}
 	}
+	if len(resp.Err) == 0 && resume != nil {
+		h.resumed, err = resume(&req, resp)
+		if err != nil {
+			return h, err
+		}
+	}
 	p, err := proto.Marshal(resp)
 	if err != nil {
-		return sid, nil, nil, err
+		return h, err
 	}
 	if err := newEncoder(conn).Encode(confSessType, "confirmSession", p); err != nil {
-		return sid, nil, nil, err
+		return h, err
 	}
-	sid, err = uuid.FromBytes(req.Sid)
-	return sid, req.Identification, req.LocalStreamNames, err
+	h.sid, err = uuid.FromBytes(resp.Sid)
+	h.identification = req.Identification
+	h.streamNames = req.LocalStreamNames
+	h.ticket = resp.ResumeTicket
+	return h, err
 }
 
-func openSessionRequest(conn *yamux.Stream, sessID []byte, token Token, id Identification, localStreamNames []string) ([]string, error) {
+func openSessionRequest(conn *yamux.Stream, sessID []byte, token Token, id Identification, localStreamNames []string, ticket []byte) (*pb.OpenSessionResponse, error) {
 	payload, err := proto.Marshal(&pb.OpenSessionRequest{
 		Sid:              sessID,
 		Token:            token,
 		LocalStreamNames: localStreamNames,
 		Identification:   id,
+		ResumeTicket:     ticket,
 	})
 	if err != nil {
 		return nil, err
@@ -611,9 +913,5 @@ func openSessionRequest(conn *yamux.Stream, sessID []byte, token Token, id Ident
 	if len(resp.Err) > 0 {
 		return nil, errors.New(resp.Err)
 	}
-	return resp.RemoteStreamNames, nil
-}
-
-func isNotConnErr(err error) bool {
-	return !strings.Contains(err.Error(), "connection refused")
+	return &resp, nil
 }
@@ -291,6 +291,13 @@ func TestWire_Session(t *testing.T) {
 		assert.Equal(t, found.ID(), sess.ID())
 	}
 
+	// the sessions are the copy of the registered sessions
+	sessions := server.Sessions()
+	for sid := range sessions {
+		delete(sessions, sid)
+	}
+	assert.Len(t, server.Sessions(), 1)
+
 	assert.Nil(t, client.Close())
 	assert.Nil(t, server.Close())
 }
@@ -552,9 +559,10 @@ func TestWire_ShutdownFail(t *testing.T) {
 	assert.Nil(t, err)
 	server.Stream("ns:stream", func(ctx context.Context, s Stream) {
 		time.Sleep(3 * time.Second)
+		// the stream is closed by the session close timeout
 		n, err := s.ReadFrom(bytes.NewReader([]byte("ok")))
-		assert.Nil(t, err)
-		assert.Equal(t, int64(2), n)
+		assert.Equal(t, ErrStreamClosed, err)
+		assert.Equal(t, int64(0), n)
 	})
 	go func() {
 		assert.Nil(t, server.Connect())
@@ -632,3 +640,193 @@ func genAddr(t *testing.T) string {
 	port := listener.Addr().(*net.TCPAddr).Port
 	return fmt.Sprintf(":%d", port)
 }
+
+func TestWire_ShutdownContext(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+
+	// server side
+	server, err := Mount(addr,
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	server.Stream("ns:slow", func(ctx context.Context, s Stream) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	server.Stream("ns:fast", func(ctx context.Context, s Stream) {})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr,
+		WithSessionOpenHook(func(s Session) {
+			initCli <- s
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("ns:slow")
+	assert.Nil(t, err)
+	defer stream.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err = server.Shutdown(ctx)
+	shutdownErr, ok := err.(*ShutdownError)
+	assert.True(t, ok)
+	assert.True(t, shutdownErr.IsCut())
+	assert.Equal(t, []string{"ns:slow"}, shutdownErr.Cut[sess.ID()])
+	assert.Nil(t, server.Shutdown(ctx))
+	assert.Len(t, server.Sessions(), 0)
+}
+
+func TestWire_ShutdownContextDrain(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	initCli := make(chan Session)
+
+	// server side
+	server, err := Mount(addr,
+		WithConnectHook(func(closer io.Closer) {
+			close(initSrv)
+		}))
+	assert.Nil(t, err)
+	server.Stream("ns:stream", func(ctx context.Context, s Stream) {
+		time.Sleep(500 * time.Millisecond)
+		_, err := s.ReadFrom(bytes.NewReader([]byte("ok")))
+		assert.Nil(t, err)
+	})
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// client side
+	client, err := Join(addr,
+		WithSessionOpenHook(func(s Session) {
+			initCli <- s
+		}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, client.Connect())
+	}()
+	sess := <-initCli
+
+	stream, err := sess.OpenStream("ns:stream")
+	assert.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.Nil(t, server.Shutdown(ctx))
+	}()
+	buf := bytes.NewBuffer(nil)
+	n, err := stream.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestWire_ConnectContext(t *testing.T) {
+	// client side
+	client, err := Join(genAddr(t), WithRetryWait(time.Hour, time.Hour))
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, client.ConnectContext(ctx))
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// server side
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- server.ConnectContext(ctx)
+	}()
+	<-initSrv
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, server.Endpoint())
+}
+
+// slowListener announces the pipe listener after the delay.
+type slowListener struct {
+	*pipeListener
+	delay time.Duration
+}
+
+func (l *slowListener) Listen(addr string) (net.Listener, error) {
+	time.Sleep(l.delay)
+	return l.pipeListener.Listen(addr)
+}
+
+func TestWire_ConnectContextSlowListen(t *testing.T) {
+	transport := &slowListener{pipeListener: newPipeListener(), delay: 200 * time.Millisecond}
+	server, err := Mount("pipe", WithTransport(transport))
+	assert.Nil(t, err)
+
+	// the context is canceled before the listener is announced
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error)
+	go func() {
+		done <- server.ConnectContext(ctx)
+	}()
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connect is not canceled while listening")
+	}
+	assert.Empty(t, server.Endpoint())
+	_, err = transport.Accept()
+	assert.NotNil(t, err)
+}
+
+func TestWire_ConnectContextCancelHandshake(t *testing.T) {
+	addr := genAddr(t)
+	initSrv := make(chan struct{})
+	server, err := Mount(addr, WithConnectHook(func(closer io.Closer) {
+		close(initSrv)
+	}))
+	assert.Nil(t, err)
+	go func() {
+		assert.Nil(t, server.Connect())
+	}()
+	<-initSrv
+
+	// the context is canceled while the client is dialing or opening the session
+	for i := 0; i < 30; i++ {
+		client, err := Join(addr, WithRetryMax(1))
+		assert.Nil(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i*50)*time.Microsecond)
+		done := make(chan error)
+		go func() {
+			done <- client.ConnectContext(ctx)
+		}()
+		select {
+		case err := <-done:
+			assert.NotNil(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("connect is not canceled after %v", time.Duration(i*50)*time.Microsecond)
+		}
+		cancel()
+	}
+	assert.Nil(t, server.Close())
+}